@@ -1,15 +1,22 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
+	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
 	api "github.com/ibp-network/ibp-geodns-monitor/src/api"
 
+	"github.com/ibp-network/ibp-geodns-monitor/src/history"
 	"github.com/ibp-network/ibp-geodns-monitor/src/monitor"
+	"github.com/ibp-network/ibp-geodns-monitor/src/notify"
+	"github.com/ibp-network/ibp-geodns-monitor/src/settings"
 
 	cfg "github.com/ibp-network/ibp-geodns-libs/config"
 	dat "github.com/ibp-network/ibp-geodns-libs/data"
@@ -33,9 +40,15 @@ func main() {
 	if version == "" {
 		version = cfg.GetVersion()
 	}
+
+	if len(os.Args) > 1 && os.Args[1] == "check" {
+		os.Exit(runCheckCommand(os.Args[2:]))
+	}
+
 	log.Log(log.Info, "IBPMonitor %s starting...", version)
 
 	cfgPath := flag.String("config", "ibpmonitor.json", "Path to the configuration file")
+	standalone := flag.Bool("standalone", false, "Run without NATS consensus, treating local results as official")
 	flag.Parse()
 
 	if _, err := os.Stat(*cfgPath); os.IsNotExist(err) {
@@ -47,9 +60,128 @@ func main() {
 	c := cfg.GetConfig()
 	log.SetLogLevel(log.ParseLogLevel(c.Local.System.LogLevel))
 
+	if err := settings.Init(*cfgPath); err != nil {
+		log.Log(log.Fatal, "Failed to load monitor settings: %v", err)
+		os.Exit(1)
+	}
+	initHistory(c, settings.Get())
+
 	dat.Init(dat.InitOptions{UseLocalOfficialCaches: true, UseUsageStats: false})
 	max.Init()
 
+	if *standalone {
+		log.Log(log.Warn, "Standalone mode: NATS consensus disabled; local results are treated as official")
+	} else {
+		startConsensus(c)
+	}
+
+	notify.Init(settings.Get(), c.Local.Nats.NodeID, c.Local.System.WorkDir)
+	workers := settings.Get().CheckWorkers
+	if workers.SeparationInterval > 0 {
+		log.Log(log.Warn, "CheckWorkers.separationInterval is deprecated; set maxChecksPerSecond instead (dispatching at %g checks/s)",
+			workers.MaxChecksPerSecond)
+	}
+	state := settings.Get().State
+	var statePath string
+	if c.Local.System.WorkDir != "" {
+		statePath = filepath.Join(c.Local.System.WorkDir, "monitor-state.json")
+	}
+	monitor.Init(monitor.InitOptions{
+		Standalone:             *standalone,
+		MaxChecksPerSecond:     workers.MaxChecksPerSecond,
+		MaxConcurrentPerMember: workers.MaxConcurrentPerMember,
+		MaxConcurrentPerIP:     workers.MaxConcurrentPerIP,
+		NodeID:                 c.Local.Nats.NodeID,
+		JitterPercent:          workers.JitterPercent,
+		StatePath:              statePath,
+		StateMaxAge:            time.Duration(state.MaxAgeMinutes) * time.Minute,
+		StateSaveInterval:      time.Duration(state.SaveIntervalSeconds) * time.Second,
+	})
+	api.Init(api.InitOptions{Standalone: *standalone})
+
+	// Set up signal handling for graceful shutdown
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+
+	<-sigChan
+	log.Log(log.Info, "Shutdown signal received, cleaning up...")
+	monitor.Shutdown()
+	notify.Shutdown()
+	history.Shutdown()
+	time.Sleep(1 * time.Second) // Give time for cleanup
+}
+
+// initHistory opens the local check history store when it is enabled.
+func initHistory(c cfg.Config, s settings.Settings) {
+	if s.History.Enabled != 1 {
+		return
+	}
+
+	dir := s.History.Dir
+	if dir == "" {
+		dir = filepath.Join(c.Local.System.WorkDir, "history")
+	}
+	retention := time.Duration(s.History.RetentionDays) * 24 * time.Hour
+	if err := history.Init(dir, retention); err != nil {
+		log.Log(log.Error, "History store disabled: %v", err)
+	}
+}
+
+// runCheckCommand implements `ibp-monitor check`: it runs one registered check
+// against a single member, prints the report as JSON and returns the exit code
+// (0 pass, 1 fail, 2 usage or setup error). Nothing is proposed to NATS.
+func runCheckCommand(args []string) int {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	cfgPath := fs.String("config", "ibpmonitor.json", "Path to the configuration file")
+	checkName := fs.String("check", "", "Name of the registered check to run (e.g. wss, ping, ssl)")
+	memberName := fs.String("member", "", "Member name to check")
+	endpoint := fs.String("endpoint", "", "Limit domain/endpoint checks to this RPC URL")
+	ipv6 := fs.Bool("ipv6", false, "Check the member's IPv6 address instead of IPv4")
+	logLevel := fs.String("log-level", "Error", "Log level while running the check")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *checkName == "" || *memberName == "" {
+		fmt.Fprintln(os.Stderr, "usage: ibp-monitor check --check <name> --member <name> [--endpoint URL] [--ipv6]")
+		return 2
+	}
+	if _, err := os.Stat(*cfgPath); os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "Configuration file not found: %s\n", *cfgPath)
+		return 2
+	}
+
+	log.SetLogLevel(log.ParseLogLevel(*logLevel))
+	cfg.Init(*cfgPath)
+	dat.Init(dat.InitOptions{UseLocalOfficialCaches: true, UseUsageStats: false})
+
+	report, err := monitor.RunCheckOnce(context.Background(), cfg.GetConfig(), monitor.RunOnceOptions{
+		Check:    *checkName,
+		Member:   *memberName,
+		Endpoint: *endpoint,
+		IPv6:     *ipv6,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "check failed to run: %v\n", err)
+		return 2
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to encode report: %v\n", err)
+		return 2
+	}
+
+	if !report.Status {
+		return 1
+	}
+	return 0
+}
+
+// startConsensus joins the NATS cluster as a monitor and waits briefly for peer
+// monitors so the first proposals have someone to vote on them.
+func startConsensus(c cfg.Config) {
 	if err := natsCommon.Connect(); err != nil {
 		log.Log(log.Fatal, "Failed to connect to NATS: %v", err)
 		os.Exit(1)
@@ -78,18 +210,6 @@ func main() {
 	} else {
 		log.Log(log.Warn, "Consensus warmup timed out with %d active monitor(s); starting checks anyway", activeMonitors)
 	}
-
-	monitor.Init()
-	api.Init()
-
-	// Set up signal handling for graceful shutdown
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-
-	<-sigChan
-	log.Log(log.Info, "Shutdown signal received, cleaning up...")
-	monitor.Shutdown()
-	time.Sleep(1 * time.Second) // Give time for cleanup
 }
 
 func waitForMonitorPeerDiscovery(timeout, interval time.Duration, countActive func() int) int {
@@ -5,6 +5,9 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/ibp-network/ibp-geodns-monitor/src/metrics"
+	"github.com/ibp-network/ibp-geodns-monitor/src/monitor"
+
 	dat "github.com/ibp-network/ibp-geodns-libs/data"
 	log "github.com/ibp-network/ibp-geodns-libs/logging"
 
@@ -14,8 +17,16 @@ import (
 var (
 	getOfficialResults = dat.GetOfficialResults
 	getLocalResults    = dat.GetLocalResults
+
+	// standalone serves local results as the official snapshot.
+	standalone bool
 )
 
+// InitOptions controls which snapshot the API serves.
+type InitOptions struct {
+	Standalone bool
+}
+
 func keySite(chk string, v6 bool) string {
 	if v6 {
 		return chk + "|v6"
@@ -127,11 +138,24 @@ func buildOfflineEndpointResults(input []dat.EndpointResult) []dat.EndpointResul
 	return out
 }
 
-func Init() {
+func Init(opts InitOptions) {
 	c := cfg.GetConfig()
+	standalone = opts.Standalone
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/results", handleResults)
+	mux.HandleFunc("/results", instrument("/results", handleResults))
+	mux.HandleFunc("/results/members/{name}", instrument("/results/members", handleMemberResults))
+	mux.HandleFunc("/history", instrument("/history", handleHistory))
+	mux.HandleFunc("/uptime", instrument("/uptime", handleUptime))
+	mux.HandleFunc("/events", countRequests("/events", handleEvents))
+	mux.Handle("/metrics", metrics.Handler())
+
+	monitor.AddResultListener(publishLocalResult)
+	proposed := make(chan struct{}, 1)
+	monitor.AddStatusListener(officialStatusListener(proposed))
+	if !standalone {
+		go watchOfficialResults(proposed)
+	}
 
 	log.Log(log.Info, "Starting serviceMonitor API on %s:%s",
 		c.Local.MonitorApi.ListenAddress,
@@ -167,6 +191,11 @@ func hasUsableSnapshot(siteResults []dat.SiteResult, domainResults []dat.DomainR
 }
 
 func selectResultsForAPI() ([]dat.SiteResult, []dat.DomainResult, []dat.EndpointResult, string) {
+	if standalone {
+		localSites, localDomains, localEndpoints := getLocalResults()
+		return localSites, localDomains, localEndpoints, "local"
+	}
+
 	offSites, offDomains, offEndpoints := getOfficialResults()
 	if hasUsableSnapshot(offSites, offDomains, offEndpoints) {
 		return offSites, offDomains, offEndpoints, "official"
@@ -182,36 +211,95 @@ func selectResultsForAPI() ([]dat.SiteResult, []dat.DomainResult, []dat.Endpoint
 }
 
 func handleResults(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseResultFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, source := buildResultsResponse(filter)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-IBP-Results-Source", source)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// handleMemberResults serves one member's site, domain and endpoint results.
+// The other /results filters still apply.
+func handleMemberResults(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseResultFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.Member = r.PathValue("name")
+
+	resp, source := buildResultsResponse(filter)
+	resp["MemberName"] = filter.Member
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-IBP-Results-Source", source)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func buildResultsResponse(filter resultFilter) (map[string]interface{}, string) {
 	offSites, offDomains, offEndpoints, source := selectResultsForAPI()
 
 	apiSites := make([]interface{}, 0)
-	for _, s := range buildOfflineSiteResults(offSites) {
-		apiSites = append(apiSites, map[string]interface{}{
-			"CheckName": s.Check.Name,
-			"IsIPv6":    s.IsIPv6,
-			"Results":   slimResults(s.Results),
-		})
+	if filter.includesType("site") {
+		for _, s := range buildOfflineSiteResults(offSites) {
+			if !filter.matchesGroup(s.Check.Name, "", false, s.IsIPv6) {
+				continue
+			}
+			results := filter.filterResults(s.Results)
+			if len(results) == 0 {
+				continue
+			}
+			apiSites = append(apiSites, map[string]interface{}{
+				"CheckName": s.Check.Name,
+				"IsIPv6":    s.IsIPv6,
+				"Results":   slimResults(results),
+			})
+		}
 	}
 
 	apiDomains := make([]interface{}, 0)
-	for _, d := range buildOfflineDomainResults(offDomains) {
-		apiDomains = append(apiDomains, map[string]interface{}{
-			"CheckName": d.Check.Name,
-			"Domain":    d.Domain,
-			"IsIPv6":    d.IsIPv6,
-			"Results":   slimResults(d.Results),
-		})
+	if filter.includesType("domain") {
+		for _, d := range buildOfflineDomainResults(offDomains) {
+			if !filter.matchesGroup(d.Check.Name, d.Domain, true, d.IsIPv6) {
+				continue
+			}
+			results := filter.filterResults(d.Results)
+			if len(results) == 0 {
+				continue
+			}
+			apiDomains = append(apiDomains, map[string]interface{}{
+				"CheckName": d.Check.Name,
+				"Domain":    d.Domain,
+				"IsIPv6":    d.IsIPv6,
+				"Results":   slimResults(results),
+			})
+		}
 	}
 
 	apiEndpoints := make([]interface{}, 0)
-	for _, e := range buildOfflineEndpointResults(offEndpoints) {
-		apiEndpoints = append(apiEndpoints, map[string]interface{}{
-			"CheckName": e.Check.Name,
-			"Domain":    e.Domain,
-			"RpcUrl":    e.RpcUrl,
-			"IsIPv6":    e.IsIPv6,
-			"Results":   slimResults(e.Results),
-		})
+	if filter.includesType("endpoint") {
+		for _, e := range buildOfflineEndpointResults(offEndpoints) {
+			if !filter.matchesGroup(e.Check.Name, e.Domain, true, e.IsIPv6) {
+				continue
+			}
+			results := filter.filterResults(e.Results)
+			if len(results) == 0 {
+				continue
+			}
+			apiEndpoints = append(apiEndpoints, map[string]interface{}{
+				"CheckName": e.Check.Name,
+				"Domain":    e.Domain,
+				"RpcUrl":    e.RpcUrl,
+				"IsIPv6":    e.IsIPv6,
+				"Results":   slimResults(results),
+			})
+		}
 	}
 
 	resp := map[string]interface{}{
@@ -219,10 +307,7 @@ func handleResults(w http.ResponseWriter, r *http.Request) {
 		"DomainResults":   apiDomains,
 		"EndpointResults": apiEndpoints,
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("X-IBP-Results-Source", source)
-	_ = json.NewEncoder(w).Encode(resp)
+	return resp, source
 }
 
 func slimResults(res []dat.Result) []interface{} {
@@ -4,9 +4,13 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/ibp-network/ibp-geodns-monitor/src/history"
+	"github.com/ibp-network/ibp-geodns-monitor/src/metrics"
+
 	cfg "github.com/ibp-network/ibp-geodns-libs/config"
 	dat "github.com/ibp-network/ibp-geodns-libs/data"
 )
@@ -112,3 +116,233 @@ func sampleSiteResults(checkName string) []dat.SiteResult {
 		},
 	}
 }
+
+func TestSelectResultsForAPIServesLocalResultsInStandaloneMode(t *testing.T) {
+	resetResultGettersForTest(t)
+	standalone = true
+	t.Cleanup(func() { standalone = false })
+
+	getOfficialResults = func() ([]dat.SiteResult, []dat.DomainResult, []dat.EndpointResult) {
+		return sampleSiteResults("official-ping"), nil, nil
+	}
+	getLocalResults = func() ([]dat.SiteResult, []dat.DomainResult, []dat.EndpointResult) {
+		return sampleSiteResults("ping"), nil, nil
+	}
+
+	sites, _, _, source := selectResultsForAPI()
+	if source != "local" {
+		t.Fatalf("expected local source in standalone mode, got %q", source)
+	}
+	if len(sites) != 1 || sites[0].Check.Name != "ping" {
+		t.Fatalf("expected local site result to be selected, got %#v", sites)
+	}
+}
+
+func TestInstrumentRecordsStatusCode(t *testing.T) {
+	handler := instrument("/test-instrument", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/test-instrument", nil))
+
+	metricsRec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(metricsRec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	want := `ibp_monitor_api_requests_total{code="418",route="/test-instrument"} 1`
+	if !strings.Contains(metricsRec.Body.String(), want) {
+		t.Fatalf("expected %q in metrics output, got:\n%s", want, metricsRec.Body.String())
+	}
+}
+
+func TestHandleResultsAppliesQueryFilters(t *testing.T) {
+	resetResultGettersForTest(t)
+
+	getOfficialResults = func() ([]dat.SiteResult, []dat.DomainResult, []dat.EndpointResult) {
+		return sampleMemberSiteResults(), sampleMemberDomainResults(), nil
+	}
+
+	testCases := []struct {
+		name        string
+		query       string
+		wantSites   int
+		wantDomains int
+		wantMembers []string
+	}{
+		{name: "unfiltered", query: "", wantSites: 2, wantDomains: 1, wantMembers: []string{"alpha", "beta", "alpha", "beta", "alpha"}},
+		{name: "member", query: "?member=beta", wantSites: 2, wantDomains: 0, wantMembers: []string{"beta", "beta"}},
+		{name: "status down", query: "?status=down", wantSites: 1, wantDomains: 0, wantMembers: []string{"beta"}},
+		{name: "family v6", query: "?family=v6", wantSites: 1, wantDomains: 0, wantMembers: []string{"alpha", "beta"}},
+		{name: "type domain", query: "?type=domain", wantSites: 0, wantDomains: 1, wantMembers: []string{"alpha"}},
+		{name: "domain", query: "?domain=RPC.example.com", wantSites: 0, wantDomains: 1, wantMembers: []string{"alpha"}},
+		{name: "check", query: "?check=ssl", wantSites: 0, wantDomains: 1, wantMembers: []string{"alpha"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handleResults(rec, httptest.NewRequest(http.MethodGet, "/results"+tc.query, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", rec.Code)
+			}
+
+			payload := decodeResultsPayload(t, rec.Body.Bytes())
+			if len(payload.SiteResults) != tc.wantSites || len(payload.DomainResults) != tc.wantDomains {
+				t.Fatalf("expected %d site and %d domain groups, got %s", tc.wantSites, tc.wantDomains, rec.Body.String())
+			}
+			if got := payload.memberNames(); len(got) != len(tc.wantMembers) {
+				t.Fatalf("expected members %v, got %v", tc.wantMembers, got)
+			}
+		})
+	}
+}
+
+func TestHandleResultsRejectsInvalidFilters(t *testing.T) {
+	rec := httptest.NewRecorder()
+	handleResults(rec, httptest.NewRequest(http.MethodGet, "/results?status=sideways", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestMemberResultsRouteReturnsOneMember(t *testing.T) {
+	resetResultGettersForTest(t)
+
+	getOfficialResults = func() ([]dat.SiteResult, []dat.DomainResult, []dat.EndpointResult) {
+		return sampleMemberSiteResults(), sampleMemberDomainResults(), nil
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/results/members/{name}", handleMemberResults)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/results/members/alpha", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("X-IBP-Results-Source"); got != "official" {
+		t.Fatalf("expected official source header, got %q", got)
+	}
+
+	payload := decodeResultsPayload(t, rec.Body.Bytes())
+	if payload.MemberName != "alpha" {
+		t.Fatalf("expected MemberName alpha, got %q", payload.MemberName)
+	}
+	for _, name := range payload.memberNames() {
+		if name != "alpha" {
+			t.Fatalf("expected only alpha results, got %v", payload.memberNames())
+		}
+	}
+	if len(payload.SiteResults) != 2 || len(payload.DomainResults) != 1 {
+		t.Fatalf("expected alpha's site and domain results, got %s", rec.Body.String())
+	}
+}
+
+type resultsPayload struct {
+	MemberName  string `json:"MemberName"`
+	SiteResults []struct {
+		Results []struct {
+			MemberName string `json:"MemberName"`
+		} `json:"Results"`
+	} `json:"SiteResults"`
+	DomainResults []struct {
+		Results []struct {
+			MemberName string `json:"MemberName"`
+		} `json:"Results"`
+	} `json:"DomainResults"`
+}
+
+func (p resultsPayload) memberNames() []string {
+	var out []string
+	for _, g := range p.SiteResults {
+		for _, r := range g.Results {
+			out = append(out, r.MemberName)
+		}
+	}
+	for _, g := range p.DomainResults {
+		for _, r := range g.Results {
+			out = append(out, r.MemberName)
+		}
+	}
+	return out
+}
+
+func decodeResultsPayload(t *testing.T, body []byte) resultsPayload {
+	t.Helper()
+	var payload resultsPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return payload
+}
+
+func sampleResult(member string, status bool, v6 bool) dat.Result {
+	r := dat.Result{Status: status, IsIPv6: v6, Checktime: time.Unix(1700000000, 0).UTC()}
+	r.Member.Details.Name = member
+	return r
+}
+
+func sampleMemberSiteResults() []dat.SiteResult {
+	return []dat.SiteResult{
+		{
+			Check:   cfg.Check{Name: "ping"},
+			Results: []dat.Result{sampleResult("alpha", true, false), sampleResult("beta", false, false)},
+		},
+		{
+			Check:   cfg.Check{Name: "ping"},
+			IsIPv6:  true,
+			Results: []dat.Result{sampleResult("alpha", true, true), sampleResult("beta", true, true)},
+		},
+	}
+}
+
+func sampleMemberDomainResults() []dat.DomainResult {
+	return []dat.DomainResult{
+		{
+			Check:   cfg.Check{Name: "ssl"},
+			Domain:  "rpc.example.com",
+			Results: []dat.Result{sampleResult("alpha", true, false)},
+		},
+	}
+}
+
+func TestHandleUptimeComputesAvailabilityFromHistory(t *testing.T) {
+	store, err := history.Open(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("history.Open returned error: %v", err)
+	}
+	defer store.Close()
+	historyStore = func() *history.Store { return store }
+	t.Cleanup(func() { historyStore = history.Default })
+
+	base := time.Unix(1700000000, 0).UTC()
+	for i, status := range []bool{true, true, true, false} {
+		_ = store.Append(history.Record{Time: base.Add(time.Duration(i) * time.Minute), Check: "wss", Member: "alpha", Service: "Polkadot", Status: status})
+	}
+
+	rec := httptest.NewRecorder()
+	handleUptime(rec, httptest.NewRequest(http.MethodGet, "/uptime?member=alpha&from=1699999000&to=1700001000", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var payload struct {
+		Uptime []history.UptimeRow
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(payload.Uptime) != 1 || payload.Uptime[0].Availability != 75 || payload.Uptime[0].Service != "Polkadot" {
+		t.Fatalf("unexpected uptime payload %s", rec.Body.String())
+	}
+}
+
+func TestHandleHistoryRequiresEnabledStore(t *testing.T) {
+	historyStore = func() *history.Store { return nil }
+	t.Cleanup(func() { historyStore = history.Default })
+
+	rec := httptest.NewRecorder()
+	handleHistory(rec, httptest.NewRequest(http.MethodGet, "/history", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,351 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ibp-network/ibp-geodns-monitor/src/monitor"
+
+	dat "github.com/ibp-network/ibp-geodns-libs/data"
+	log "github.com/ibp-network/ibp-geodns-libs/logging"
+)
+
+const (
+	// eventBacklog is how many recent events are kept for Last-Event-ID resume.
+	eventBacklog = 2000
+	// subscriberBuffer bounds the events queued for one client; a client
+	// that falls this far behind is disconnected and must resume.
+	subscriberBuffer = 256
+	eventKeepalive   = 15 * time.Second
+
+	// With consensus, official results are diffed every officialSettlePoll
+	// for officialSettleWindow after this monitor proposes a change, and
+	// every officialIdlePoll otherwise.
+	officialSettlePoll   = time.Second
+	officialSettleWindow = 30 * time.Second
+	officialIdlePoll     = 30 * time.Second
+)
+
+// streamEvent is one entry on /events. Kind is "result" for a local check
+// completion and "status" for a change in the served official snapshot.
+type streamEvent struct {
+	ID         uint64                 `json:"-"`
+	Kind       string                 `json:"-"`
+	Type       string                 `json:"Type"`
+	CheckName  string                 `json:"CheckName"`
+	Domain     string                 `json:"Domain,omitempty"`
+	RpcUrl     string                 `json:"RpcUrl,omitempty"`
+	Status     bool                   `json:"Status"`
+	MemberName string                 `json:"MemberName"`
+	ErrorText  string                 `json:"ErrorText"`
+	Data       map[string]interface{} `json:"Data"`
+	IsIPv6     bool                   `json:"IsIPv6"`
+	Checktime  string                 `json:"Checktime"`
+}
+
+// eventHub fans events out to /events clients and keeps a short backlog so
+// reconnecting clients can resume from their Last-Event-ID.
+type eventHub struct {
+	mu      sync.Mutex
+	nextID  uint64
+	backlog []streamEvent
+	subs    map[chan streamEvent]struct{}
+}
+
+var eventStream = newEventHub()
+
+func newEventHub() *eventHub {
+	return &eventHub{nextID: 1, subs: make(map[chan streamEvent]struct{})}
+}
+
+// publish assigns ev the next ID and hands it to every subscriber without
+// blocking; subscribers whose buffer is full are dropped.
+func (h *eventHub) publish(ev streamEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ev.ID = h.nextID
+	h.nextID++
+	h.backlog = append(h.backlog, ev)
+	if over := len(h.backlog) - eventBacklog; over > 0 {
+		h.backlog = h.backlog[over:]
+	}
+
+	for ch := range h.subs {
+		select {
+		case ch <- ev:
+		default:
+			delete(h.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// subscribe returns the backlog after lastID and a channel of later events.
+// gap reports that events after lastID have already left the backlog.
+func (h *eventHub) subscribe(lastID uint64, resume bool) (replay []streamEvent, gap bool, ch chan streamEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if resume {
+		// An ID from the future means this process restarted; IDs start over.
+		if lastID >= h.nextID {
+			lastID = 0
+			gap = true
+		}
+		if len(h.backlog) > 0 && lastID+1 < h.backlog[0].ID {
+			gap = true
+		}
+		for _, ev := range h.backlog {
+			if ev.ID > lastID {
+				replay = append(replay, ev)
+			}
+		}
+	}
+
+	ch = make(chan streamEvent, subscriberBuffer)
+	h.subs[ch] = struct{}{}
+	return replay, gap, ch
+}
+
+func (h *eventHub) unsubscribe(ch chan streamEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[ch]; ok {
+		delete(h.subs, ch)
+		close(ch)
+	}
+}
+
+// matchesEvent applies the /results filters to a single event.
+func (f resultFilter) matchesEvent(ev streamEvent) bool {
+	if !f.includesType(ev.Type) || !f.matchesGroup(ev.CheckName, ev.Domain, ev.Type != "site", ev.IsIPv6) {
+		return false
+	}
+	if f.Member != "" && !strings.EqualFold(f.Member, ev.MemberName) {
+		return false
+	}
+	if f.Status == "up" && !ev.Status || f.Status == "down" && ev.Status {
+		return false
+	}
+	return true
+}
+
+// handleEvents streams events as Server-Sent Events. It accepts the /results
+// filters and resumes after the Last-Event-ID header (or lastEventId query
+// parameter). A "resync" event means some events were missed and the client
+// should reload /results.
+func handleEvents(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseResultFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rawLast := r.Header.Get("Last-Event-ID")
+	if rawLast == "" {
+		rawLast = r.URL.Query().Get("lastEventId")
+	}
+	var lastID uint64
+	if rawLast != "" {
+		lastID, err = strconv.ParseUint(strings.TrimSpace(rawLast), 10, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid Last-Event-ID %q", rawLast), http.StatusBadRequest)
+			return
+		}
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	replay, gap, ch := eventStream.subscribe(lastID, rawLast != "")
+	defer eventStream.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", (5 * time.Second).Milliseconds())
+	if gap {
+		fmt.Fprint(w, "event: resync\ndata: {}\n\n")
+	}
+	for _, ev := range replay {
+		if filter.matchesEvent(ev) {
+			writeStreamEvent(w, ev)
+		}
+	}
+	flusher.Flush()
+
+	keepalive := time.NewTicker(eventKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case ev, open := <-ch:
+			if !open {
+				// Too slow; the client reconnects with its Last-Event-ID.
+				return
+			}
+			if !filter.matchesEvent(ev) {
+				continue
+			}
+			writeStreamEvent(w, ev)
+			flusher.Flush()
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeStreamEvent(w http.ResponseWriter, ev streamEvent) {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		log.Log(log.Warn, "Failed to encode event %d: %v", ev.ID, err)
+		return
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Kind, body)
+}
+
+// publishLocalResult streams a local check completion.
+func publishLocalResult(r monitor.CheckResult) {
+	eventStream.publish(streamEvent{
+		Kind:       "result",
+		Type:       r.Type,
+		CheckName:  r.CheckName,
+		Domain:     r.Domain,
+		RpcUrl:     r.Endpoint,
+		Status:     r.Status,
+		MemberName: r.Member,
+		ErrorText:  r.ErrorText,
+		Data:       r.Data,
+		IsIPv6:     r.IsIPv6,
+		Checktime:  r.Checktime.Format(time.RFC3339),
+	})
+}
+
+// officialWatcher diffs successive official snapshots and streams every
+// status change. The first snapshot only seeds the comparison.
+type officialWatcher struct {
+	seeded bool
+	last   map[string]bool
+}
+
+func (o *officialWatcher) poll() []streamEvent {
+	sites, domains, endpoints := getOfficialResults()
+
+	current := make(map[string]bool)
+	var changed []streamEvent
+	observe := func(ev streamEvent) {
+		key := strings.Join([]string{ev.Type, ev.CheckName, ev.Domain, ev.RpcUrl, ev.MemberName, strconv.FormatBool(ev.IsIPv6)}, "|")
+		current[key] = ev.Status
+		if prev, ok := o.last[key]; o.seeded && (!ok || prev != ev.Status) {
+			changed = append(changed, ev)
+		}
+	}
+
+	for _, s := range buildOfflineSiteResults(sites) {
+		for _, r := range s.Results {
+			observe(officialEvent("site", s.Check.Name, "", "", s.IsIPv6, r))
+		}
+	}
+	for _, d := range buildOfflineDomainResults(domains) {
+		for _, r := range d.Results {
+			observe(officialEvent("domain", d.Check.Name, d.Domain, "", d.IsIPv6, r))
+		}
+	}
+	for _, e := range buildOfflineEndpointResults(endpoints) {
+		for _, r := range e.Results {
+			observe(officialEvent("endpoint", e.Check.Name, e.Domain, e.RpcUrl, e.IsIPv6, r))
+		}
+	}
+
+	o.last = current
+	o.seeded = true
+	return changed
+}
+
+func officialEvent(resultType, checkName, domain, rpcURL string, isIPv6 bool, r dat.Result) streamEvent {
+	return streamEvent{
+		Kind:       "status",
+		Type:       resultType,
+		CheckName:  checkName,
+		Domain:     domain,
+		RpcUrl:     rpcURL,
+		Status:     r.Status,
+		MemberName: r.Member.Details.Name,
+		ErrorText:  r.ErrorText,
+		Data:       r.Data,
+		IsIPv6:     isIPv6,
+		Checktime:  r.Checktime.Format(time.RFC3339),
+	}
+}
+
+// watchOfficialResults streams official status changes under consensus.
+// Official results are written by ibp-geodns-libs when a NATS round
+// finalizes, and the lib offers no callback for that, so the snapshot is
+// diffed quickly while a round this monitor started settles (signalled on
+// proposed) and rarely otherwise, to catch rounds started by other monitors.
+func watchOfficialResults(proposed <-chan struct{}) {
+	var watcher officialWatcher
+	watcher.poll()
+
+	timer := time.NewTimer(officialIdlePoll)
+	defer timer.Stop()
+	var settleUntil time.Time
+	for {
+		select {
+		case <-proposed:
+			settleUntil = time.Now().Add(officialSettleWindow)
+		case <-timer.C:
+		}
+		for _, ev := range watcher.poll() {
+			eventStream.publish(ev)
+		}
+		if time.Now().Before(settleUntil) {
+			timer.Reset(officialSettlePoll)
+		} else {
+			timer.Reset(officialIdlePoll)
+		}
+	}
+}
+
+// officialStatusListener feeds monitor status events to the official stream.
+// In standalone mode local results are the official ones, so each local
+// transition is published straight away. Under consensus a proposal wakes
+// watchOfficialResults through proposed.
+func officialStatusListener(proposed chan<- struct{}) func(monitor.StatusEvent) {
+	return func(ev monitor.StatusEvent) {
+		switch {
+		case standalone && ev.Kind == monitor.StatusEventTransition:
+			eventStream.publish(streamEvent{
+				Kind:       "status",
+				Type:       ev.Type,
+				CheckName:  ev.CheckName,
+				Domain:     ev.Domain,
+				RpcUrl:     ev.Endpoint,
+				Status:     ev.Status,
+				MemberName: ev.Member,
+				ErrorText:  ev.ErrorText,
+				Data:       ev.Data,
+				IsIPv6:     ev.IsIPv6,
+				Checktime:  ev.Time.Format(time.RFC3339),
+			})
+		case !standalone && ev.Kind == monitor.StatusEventProposal:
+			select {
+			case proposed <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	cfg "github.com/ibp-network/ibp-geodns-libs/config"
+	dat "github.com/ibp-network/ibp-geodns-libs/data"
+
+	"github.com/ibp-network/ibp-geodns-monitor/src/monitor"
+)
+
+func resetEventStreamForTest(t *testing.T) {
+	t.Helper()
+	prev := eventStream
+	eventStream = newEventHub()
+	t.Cleanup(func() { eventStream = prev })
+}
+
+func TestEventHubResumesAfterLastEventID(t *testing.T) {
+	hub := newEventHub()
+	for _, name := range []string{"a", "b", "c"} {
+		hub.publish(streamEvent{Kind: "result", CheckName: name})
+	}
+
+	replay, gap, ch := hub.subscribe(1, true)
+	defer hub.unsubscribe(ch)
+	if gap || len(replay) != 2 || replay[0].ID != 2 || replay[1].CheckName != "c" {
+		t.Fatalf("expected events 2 and 3 without gap, got gap=%v %#v", gap, replay)
+	}
+
+	replay, gap, ch2 := hub.subscribe(99, true)
+	defer hub.unsubscribe(ch2)
+	if !gap || len(replay) != 3 {
+		t.Fatalf("expected full replay with gap for an ID from a previous process, got gap=%v %d", gap, len(replay))
+	}
+
+	replay, gap, ch3 := hub.subscribe(0, false)
+	defer hub.unsubscribe(ch3)
+	if gap || len(replay) != 0 {
+		t.Fatalf("expected no replay without Last-Event-ID, got %#v", replay)
+	}
+}
+
+func TestEventHubReportsGapWhenBacklogEvicted(t *testing.T) {
+	hub := newEventHub()
+	for i := 0; i < eventBacklog+5; i++ {
+		hub.publish(streamEvent{Kind: "result"})
+	}
+	replay, gap, ch := hub.subscribe(2, true)
+	defer hub.unsubscribe(ch)
+	if !gap || len(replay) != eventBacklog {
+		t.Fatalf("expected gap and full backlog, got gap=%v %d", gap, len(replay))
+	}
+}
+
+func TestHandleEventsStreamsFilteredEvents(t *testing.T) {
+	resetEventStreamForTest(t)
+	eventStream.publish(streamEvent{Kind: "result", Type: "site", CheckName: "ping", MemberName: "alpha", Status: true})
+	eventStream.publish(streamEvent{Kind: "result", Type: "site", CheckName: "ping", MemberName: "beta", Status: false})
+
+	srv := httptest.NewServer(instrument("/events", handleEvents))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/events?member=beta", nil)
+	req.Header.Set("Last-Event-ID", "0")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+
+	go eventStream.publish(streamEvent{Kind: "status", Type: "domain", CheckName: "ssl", Domain: "rpc.example.com", MemberName: "beta", Status: true})
+
+	var ids, kinds []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() && len(kinds) < 2 {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			ids = append(ids, strings.TrimPrefix(line, "id: "))
+		case strings.HasPrefix(line, "event: "):
+			kinds = append(kinds, strings.TrimPrefix(line, "event: "))
+		case strings.HasPrefix(line, "data: ") && strings.Contains(line, `"alpha"`):
+			t.Fatalf("member filter not applied: %s", line)
+		}
+	}
+	if strings.Join(ids, ",") != "2,3" || strings.Join(kinds, ",") != "result,status" {
+		t.Fatalf("expected ids 2,3 as result,status; got ids=%v kinds=%v", ids, kinds)
+	}
+}
+
+func TestHandleEventsRejectsInvalidLastEventID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("Last-Event-ID", "abc")
+	rec := httptest.NewRecorder()
+	handleEvents(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+}
+
+func TestOfficialWatcherEmitsStatusChanges(t *testing.T) {
+	resetResultGettersForTest(t)
+
+	status := true
+	getOfficialResults = func() ([]dat.SiteResult, []dat.DomainResult, []dat.EndpointResult) {
+		r := sampleResult("alpha", status, false)
+		return []dat.SiteResult{{Check: cfg.Check{Name: "ping"}, Results: []dat.Result{r}}}, nil, nil
+	}
+
+	var watcher officialWatcher
+	if got := watcher.poll(); len(got) != 0 {
+		t.Fatalf("first poll should only seed, got %#v", got)
+	}
+	if got := watcher.poll(); len(got) != 0 {
+		t.Fatalf("unchanged snapshot should emit nothing, got %#v", got)
+	}
+
+	status = false
+	got := watcher.poll()
+	if len(got) != 1 || got[0].Kind != "status" || got[0].Status || got[0].MemberName != "alpha" {
+		t.Fatalf("expected one down status event for alpha, got %#v", got)
+	}
+}
+
+func TestOfficialStatusListenerPublishesStandaloneTransitions(t *testing.T) {
+	standalone = true
+	t.Cleanup(func() { standalone = false })
+	resetEventStreamForTest(t)
+
+	_, _, ch := eventStream.subscribe(0, false)
+	defer eventStream.unsubscribe(ch)
+
+	proposed := make(chan struct{}, 1)
+	listen := officialStatusListener(proposed)
+	listen(monitor.StatusEvent{Kind: monitor.StatusEventTransition, Type: "site", CheckName: "ping", Member: "alpha", Time: time.Now()})
+
+	select {
+	case ev := <-ch:
+		if ev.Kind != "status" || ev.MemberName != "alpha" || ev.Status {
+			t.Fatalf("unexpected event %#v", ev)
+		}
+	default:
+		t.Fatalf("expected the transition to be published as a status event")
+	}
+	if len(proposed) != 0 {
+		t.Fatalf("expected no watcher wakeup in standalone mode")
+	}
+}
+
+func TestOfficialStatusListenerWakesWatcherOnProposal(t *testing.T) {
+	resetEventStreamForTest(t)
+	_, _, ch := eventStream.subscribe(0, false)
+	defer eventStream.unsubscribe(ch)
+
+	proposed := make(chan struct{}, 1)
+	listen := officialStatusListener(proposed)
+	listen(monitor.StatusEvent{Kind: monitor.StatusEventTransition, Type: "site", CheckName: "ping", Member: "alpha"})
+	listen(monitor.StatusEvent{Kind: monitor.StatusEventProposal, Type: "site", CheckName: "ping", Member: "alpha"})
+	listen(monitor.StatusEvent{Kind: monitor.StatusEventProposal, Type: "site", CheckName: "ping", Member: "beta"})
+
+	if len(proposed) != 1 {
+		t.Fatalf("expected one pending wakeup, got %d", len(proposed))
+	}
+	if len(ch) != 0 {
+		t.Fatalf("expected local transitions not to be published as official under consensus")
+	}
+}
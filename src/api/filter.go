@@ -0,0 +1,85 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	dat "github.com/ibp-network/ibp-geodns-libs/data"
+)
+
+// resultFilter narrows a results snapshot. The zero value matches everything.
+type resultFilter struct {
+	Member string
+	Check  string
+	Domain string
+	Status string // "up", "down"
+	Family string // "v4", "v6"
+	Type   string // "site", "domain", "endpoint"
+}
+
+func parseResultFilter(q url.Values) (resultFilter, error) {
+	f := resultFilter{
+		Member: strings.TrimSpace(q.Get("member")),
+		Check:  strings.TrimSpace(q.Get("check")),
+		Domain: strings.ToLower(strings.TrimSpace(q.Get("domain"))),
+		Status: strings.ToLower(strings.TrimSpace(q.Get("status"))),
+		Family: strings.ToLower(strings.TrimSpace(q.Get("family"))),
+		Type:   strings.ToLower(strings.TrimSpace(q.Get("type"))),
+	}
+
+	switch f.Status {
+	case "", "up", "down":
+	default:
+		return resultFilter{}, fmt.Errorf("invalid status %q: expected up or down", f.Status)
+	}
+	switch f.Family {
+	case "", "v4", "v6":
+	default:
+		return resultFilter{}, fmt.Errorf("invalid family %q: expected v4 or v6", f.Family)
+	}
+	switch f.Type {
+	case "", "site", "domain", "endpoint":
+	default:
+		return resultFilter{}, fmt.Errorf("invalid type %q: expected site, domain or endpoint", f.Type)
+	}
+
+	return f, nil
+}
+
+func (f resultFilter) includesType(resultType string) bool {
+	return f.Type == "" || f.Type == resultType
+}
+
+// matchesGroup applies the check, domain and family filters to a result group.
+// Site groups have no domain, so a domain filter excludes them.
+func (f resultFilter) matchesGroup(checkName, domain string, hasDomain bool, isIPv6 bool) bool {
+	if f.Check != "" && !strings.EqualFold(f.Check, checkName) {
+		return false
+	}
+	if f.Domain != "" && (!hasDomain || !strings.EqualFold(f.Domain, domain)) {
+		return false
+	}
+	if f.Family == "v4" && isIPv6 || f.Family == "v6" && !isIPv6 {
+		return false
+	}
+	return true
+}
+
+// filterResults applies the member and status filters to a group's results.
+func (f resultFilter) filterResults(results []dat.Result) []dat.Result {
+	if f.Member == "" && f.Status == "" {
+		return results
+	}
+	out := make([]dat.Result, 0, len(results))
+	for _, r := range results {
+		if f.Member != "" && !strings.EqualFold(f.Member, r.Member.Details.Name) {
+			continue
+		}
+		if f.Status == "up" && !r.Status || f.Status == "down" && r.Status {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ibp-network/ibp-geodns-monitor/src/history"
+)
+
+const (
+	defaultHistoryWindow = 24 * time.Hour
+	defaultHistoryLimit  = 1000
+	maxHistoryLimit      = 50000
+)
+
+var historyStore = history.Default
+
+func handleHistory(w http.ResponseWriter, r *http.Request) {
+	store := historyStore()
+	if store == nil {
+		http.Error(w, "history store is disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	q, err := parseHistoryQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q.Limit = defaultHistoryLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			http.Error(w, fmt.Sprintf("invalid limit %q", raw), http.StatusBadRequest)
+			return
+		}
+		q.Limit = min(limit, maxHistoryLimit)
+	}
+
+	records, err := store.Query(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"From":    q.From.Format(time.RFC3339),
+		"To":      q.To.Format(time.RFC3339),
+		"Records": records,
+	})
+}
+
+func handleUptime(w http.ResponseWriter, r *http.Request) {
+	store := historyStore()
+	if store == nil {
+		http.Error(w, "history store is disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	q, err := parseHistoryQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	records, err := store.Query(q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"From":   q.From.Format(time.RFC3339),
+		"To":     q.To.Format(time.RFC3339),
+		"Uptime": history.Uptime(records),
+	})
+}
+
+// parseHistoryQuery reads member, check, from and to. Times are RFC3339 or
+// unix seconds; the window defaults to the last 24 hours.
+func parseHistoryQuery(v url.Values) (history.Query, error) {
+	q := history.Query{
+		Member: strings.TrimSpace(v.Get("member")),
+		Check:  strings.TrimSpace(v.Get("check")),
+		To:     time.Now().UTC(),
+	}
+
+	if raw := v.Get("to"); raw != "" {
+		t, err := parseQueryTime(raw)
+		if err != nil {
+			return history.Query{}, fmt.Errorf("invalid to: %v", err)
+		}
+		q.To = t
+	}
+	q.From = q.To.Add(-defaultHistoryWindow)
+	if raw := v.Get("from"); raw != "" {
+		t, err := parseQueryTime(raw)
+		if err != nil {
+			return history.Query{}, fmt.Errorf("invalid from: %v", err)
+		}
+		q.From = t
+	}
+	if !q.From.Before(q.To) {
+		return history.Query{}, fmt.Errorf("from must be before to")
+	}
+	return q, nil
+}
+
+func parseQueryTime(raw string) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if secs, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Unix(secs, 0).UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected RFC3339 or unix seconds, got %q", raw)
+	}
+	return t.UTC(), nil
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ibp-network/ibp-geodns-monitor/src/metrics"
+)
+
+var (
+	apiRequests = metrics.NewCounterVec("ibp_monitor_api_requests_total",
+		"Monitor API requests by route and status code.",
+		"route", "code")
+	apiDuration = metrics.NewHistogramVec("ibp_monitor_api_request_duration_seconds",
+		"Monitor API request latency by route.",
+		[]float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5},
+		"route")
+)
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Flush lets streaming handlers such as /events flush through the recorder.
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// countRequests counts requests like instrument but records no duration, for
+// streams whose lifetime would swamp the latency histogram.
+func countRequests(route string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		h(rec, r)
+		apiRequests.WithLabelValues(route, strconv.Itoa(rec.status)).Inc()
+	}
+}
+
+// instrument records request counts and latency for route.
+func instrument(route string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		h(rec, r)
+		apiRequests.WithLabelValues(route, strconv.Itoa(rec.status)).Inc()
+		apiDuration.WithLabelValues(route).Observe(time.Since(start).Seconds())
+	}
+}
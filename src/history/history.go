@@ -0,0 +1,370 @@
+// Package history keeps a local, file-backed log of every check execution so
+// availability can be computed from this monitor's own observations. Records
+// are appended to one JSON-lines file per UTC day; retention deletes whole days.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/ibp-network/ibp-geodns-libs/logging"
+)
+
+const dayLayout = "2006-01-02"
+
+// Record is one check execution.
+type Record struct {
+	Time      time.Time
+	Type      string
+	Check     string
+	Member    string
+	Service   string `json:",omitempty"`
+	Domain    string `json:",omitempty"`
+	Endpoint  string `json:",omitempty"`
+	IsIPv6    bool
+	Status    bool
+	ErrorText string  `json:",omitempty"`
+	LatencyMs float64 `json:",omitempty"`
+}
+
+// Query selects records by member and check within [From, To).
+type Query struct {
+	Member string
+	Check  string
+	From   time.Time
+	To     time.Time
+	Limit  int
+}
+
+type Store struct {
+	dir       string
+	retention time.Duration
+
+	mu      sync.Mutex
+	day     string
+	file    *os.File
+	writer  *bufio.Writer
+	stopCh  chan struct{}
+	stopped sync.Once
+}
+
+var (
+	defaultMu    sync.RWMutex
+	defaultStore *Store
+)
+
+// Open creates dir if needed and returns a store that keeps retention worth of days.
+func Open(dir string, retention time.Duration) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create history dir: %w", err)
+	}
+	return &Store{dir: dir, retention: retention, stopCh: make(chan struct{})}, nil
+}
+
+// Init opens the process-wide store and starts hourly retention pruning.
+func Init(dir string, retention time.Duration) error {
+	s, err := Open(dir, retention)
+	if err != nil {
+		return err
+	}
+	if err := s.Prune(time.Now()); err != nil {
+		log.Log(log.Warn, "History prune failed: %v", err)
+	}
+	go s.pruneLoop(time.Hour)
+
+	defaultMu.Lock()
+	prev := defaultStore
+	defaultStore = s
+	defaultMu.Unlock()
+
+	if prev != nil {
+		_ = prev.Close()
+	}
+	log.Log(log.Info, "History store enabled at %s (retention %v)", dir, retention)
+	return nil
+}
+
+// Default returns the process-wide store, or nil when history is disabled.
+func Default() *Store {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultStore
+}
+
+// Append writes r to the process-wide store; it is a no-op when history is disabled.
+func Append(r Record) {
+	s := Default()
+	if s == nil {
+		return
+	}
+	if err := s.Append(r); err != nil {
+		log.Log(log.Warn, "History append failed: %v", err)
+	}
+}
+
+// Shutdown flushes and closes the process-wide store.
+func Shutdown() {
+	defaultMu.Lock()
+	s := defaultStore
+	defaultStore = nil
+	defaultMu.Unlock()
+
+	if s != nil {
+		_ = s.Close()
+	}
+}
+
+func (s *Store) Append(r Record) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	day := r.Time.UTC().Format(dayLayout)
+	if s.file == nil || s.day != day {
+		if err := s.rotateLocked(day); err != nil {
+			return err
+		}
+	}
+	if _, err := s.writer.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return s.writer.Flush()
+}
+
+func (s *Store) rotateLocked(day string) error {
+	if err := s.closeFileLocked(); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.dayPath(day), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open history file: %w", err)
+	}
+	s.file = f
+	s.writer = bufio.NewWriter(f)
+	s.day = day
+	return nil
+}
+
+func (s *Store) closeFileLocked() error {
+	if s.file == nil {
+		return nil
+	}
+	flushErr := s.writer.Flush()
+	closeErr := s.file.Close()
+	s.file, s.writer, s.day = nil, nil, ""
+	if flushErr != nil {
+		return flushErr
+	}
+	return closeErr
+}
+
+func (s *Store) Close() error {
+	s.stopped.Do(func() { close(s.stopCh) })
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closeFileLocked()
+}
+
+func (s *Store) dayPath(day string) string {
+	return filepath.Join(s.dir, day+".jsonl")
+}
+
+// days lists the stored day files in ascending order.
+func (s *Store) days() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".jsonl") {
+			continue
+		}
+		day := strings.TrimSuffix(name, ".jsonl")
+		if _, err := time.Parse(dayLayout, day); err != nil {
+			continue
+		}
+		out = append(out, day)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// Prune deletes day files that ended before now minus the retention period.
+func (s *Store) Prune(now time.Time) error {
+	if s.retention <= 0 {
+		return nil
+	}
+	days, err := s.days()
+	if err != nil {
+		return err
+	}
+	cutoff := now.Add(-s.retention).UTC().Format(dayLayout)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, day := range days {
+		if day >= cutoff || day == s.day {
+			continue
+		}
+		if err := os.Remove(s.dayPath(day)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) pruneLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Prune(time.Now()); err != nil {
+				log.Log(log.Warn, "History prune failed: %v", err)
+			}
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// Query returns matching records in time order. A positive Limit keeps the newest records.
+func (s *Store) Query(q Query) ([]Record, error) {
+	days, err := s.days()
+	if err != nil {
+		return nil, err
+	}
+
+	fromDay, toDay := "", ""
+	if !q.From.IsZero() {
+		fromDay = q.From.UTC().Format(dayLayout)
+	}
+	if !q.To.IsZero() {
+		toDay = q.To.UTC().Format(dayLayout)
+	}
+
+	out := make([]Record, 0)
+	for _, day := range days {
+		if fromDay != "" && day < fromDay || toDay != "" && day > toDay {
+			continue
+		}
+		if err := s.scanDay(day, func(r Record) {
+			if q.matches(r) {
+				out = append(out, r)
+			}
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Time.Before(out[j].Time) })
+	if q.Limit > 0 && len(out) > q.Limit {
+		out = out[len(out)-q.Limit:]
+	}
+	return out, nil
+}
+
+func (s *Store) scanDay(day string, fn func(Record)) error {
+	f, err := os.Open(s.dayPath(day))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			// A torn final line from a crash shouldn't hide the rest of the day.
+			continue
+		}
+		fn(r)
+	}
+	return scanner.Err()
+}
+
+func (q Query) matches(r Record) bool {
+	if q.Member != "" && !strings.EqualFold(q.Member, r.Member) {
+		return false
+	}
+	if q.Check != "" && !strings.EqualFold(q.Check, r.Check) {
+		return false
+	}
+	if !q.From.IsZero() && r.Time.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !r.Time.Before(q.To) {
+		return false
+	}
+	return true
+}
+
+// UptimeRow is the availability of one member, service and IP family.
+type UptimeRow struct {
+	Member       string
+	Service      string
+	Family       string
+	Checks       int
+	Up           int
+	Down         int
+	Availability float64 // percent of checks that passed
+}
+
+// Uptime groups records by member, service and IP family. Site checks have
+// no service and are grouped under an empty Service.
+func Uptime(records []Record) []UptimeRow {
+	rows := make(map[string]*UptimeRow)
+	for _, r := range records {
+		family := "v4"
+		if r.IsIPv6 {
+			family = "v6"
+		}
+		key := r.Member + "|" + r.Service + "|" + family
+		row, ok := rows[key]
+		if !ok {
+			row = &UptimeRow{Member: r.Member, Service: r.Service, Family: family}
+			rows[key] = row
+		}
+		row.Checks++
+		if r.Status {
+			row.Up++
+		} else {
+			row.Down++
+		}
+	}
+
+	out := make([]UptimeRow, 0, len(rows))
+	for _, row := range rows {
+		if row.Checks > 0 {
+			row.Availability = float64(row.Up) * 100 / float64(row.Checks)
+		}
+		out = append(out, *row)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Member != out[j].Member {
+			return out[i].Member < out[j].Member
+		}
+		if out[i].Service != out[j].Service {
+			return out[i].Service < out[j].Service
+		}
+		return out[i].Family < out[j].Family
+	})
+	return out
+}
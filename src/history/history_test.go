@@ -0,0 +1,100 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStoreAppendQueryAcrossDays(t *testing.T) {
+	store, err := Open(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	defer store.Close()
+
+	day1 := time.Date(2026, 3, 1, 23, 0, 0, 0, time.UTC)
+	day2 := day1.Add(2 * time.Hour)
+	for _, r := range []Record{
+		{Time: day1, Check: "wss", Member: "alpha", Status: true},
+		{Time: day1.Add(time.Minute), Check: "ping", Member: "alpha", Status: true},
+		{Time: day2, Check: "wss", Member: "alpha", Status: false, ErrorText: "timeout"},
+		{Time: day2, Check: "wss", Member: "beta", Status: true},
+	} {
+		if err := store.Append(r); err != nil {
+			t.Fatalf("Append returned error: %v", err)
+		}
+	}
+
+	got, err := store.Query(Query{Member: "alpha", Check: "wss"})
+	if err != nil {
+		t.Fatalf("Query returned error: %v", err)
+	}
+	if len(got) != 2 || !got[0].Time.Equal(day1) || got[1].ErrorText != "timeout" {
+		t.Fatalf("unexpected query result %#v", got)
+	}
+
+	got, err = store.Query(Query{Check: "wss", From: day2})
+	if err != nil {
+		t.Fatalf("Query returned error: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected two records from day two, got %#v", got)
+	}
+
+	got, err = store.Query(Query{Limit: 1})
+	if err != nil {
+		t.Fatalf("Query returned error: %v", err)
+	}
+	if len(got) != 1 || !got[0].Time.Equal(day2) {
+		t.Fatalf("expected limit to keep the newest record, got %#v", got)
+	}
+}
+
+func TestStorePruneRemovesExpiredDays(t *testing.T) {
+	dir := t.TempDir()
+	store, err := Open(dir, 48*time.Hour)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	for _, day := range []string{"2026-03-01", "2026-03-08", "2026-03-09"} {
+		if err := os.WriteFile(filepath.Join(dir, day+".jsonl"), nil, 0o644); err != nil {
+			t.Fatalf("failed to seed day file: %v", err)
+		}
+	}
+
+	if err := store.Prune(now); err != nil {
+		t.Fatalf("Prune returned error: %v", err)
+	}
+	days, err := store.days()
+	if err != nil {
+		t.Fatalf("days returned error: %v", err)
+	}
+	if len(days) != 2 || days[0] != "2026-03-08" {
+		t.Fatalf("expected only retained days, got %v", days)
+	}
+}
+
+func TestUptimeGroupsByMemberServiceAndFamily(t *testing.T) {
+	rows := Uptime([]Record{
+		{Member: "alpha", Service: "Polkadot", Status: true},
+		{Member: "alpha", Service: "Polkadot", Status: false},
+		{Member: "alpha", Service: "Polkadot", Status: true},
+		{Member: "alpha", Service: "Polkadot", Status: true},
+		{Member: "alpha", Service: "Polkadot", IsIPv6: true, Status: false},
+	})
+
+	if len(rows) != 2 {
+		t.Fatalf("expected two rows, got %#v", rows)
+	}
+	if rows[0].Family != "v4" || rows[0].Checks != 4 || rows[0].Availability != 75 {
+		t.Fatalf("unexpected v4 row %#v", rows[0])
+	}
+	if rows[1].Family != "v6" || rows[1].Availability != 0 {
+		t.Fatalf("unexpected v6 row %#v", rows[1])
+	}
+}
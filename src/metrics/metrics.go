@@ -0,0 +1,49 @@
+// Package metrics holds the Prometheus registry the monitor and its API export
+// on /metrics. Metrics are registered from package-level variables, so a
+// duplicate name fails at startup rather than during a scrape.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry collects every monitor metric plus the Go runtime and process
+// collectors.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler serves the registry for Prometheus scrapes.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// NewCounterVec registers a counter labelled by labels. Call it only while
+// initialising package variables; it panics on a duplicate name.
+func NewCounterVec(name, help string, labels ...string) *prometheus.CounterVec {
+	return factory.NewCounterVec(prometheus.CounterOpts{Name: name, Help: help}, labels)
+}
+
+// NewHistogramVec registers a histogram with buckets, labelled by labels.
+// Like NewCounterVec it is meant for package initialisation only.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *prometheus.HistogramVec {
+	return factory.NewHistogramVec(prometheus.HistogramOpts{Name: name, Help: help, Buckets: buckets}, labels)
+}
+
+// NewGaugeFunc registers an unlabelled gauge whose value is read at scrape
+// time. Like NewCounterVec it is meant for package initialisation only.
+func NewGaugeFunc(name, help string, fn func() float64) prometheus.GaugeFunc {
+	return factory.NewGaugeFunc(prometheus.GaugeOpts{Name: name, Help: help}, fn)
+}
@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	testResults  = NewCounterVec("test_results_total", "Results by status.", "check", "status")
+	testDuration = NewHistogramVec("test_duration_seconds", "Durations.", []float64{0.5, 1}, "check")
+	_            = NewGaugeFunc("test_queue_depth", "Queue depth.", func() float64 { return 7 })
+)
+
+func TestHandlerRendersCountersAndHistograms(t *testing.T) {
+	testResults.WithLabelValues("wss", "pass").Inc()
+	testResults.WithLabelValues("wss", "pass").Inc()
+	testResults.WithLabelValues("wss", `fa"il`).Inc()
+	testDuration.WithLabelValues("wss").Observe(0.2)
+	testDuration.WithLabelValues("wss").Observe(0.7)
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	out := rec.Body.String()
+
+	for _, want := range []string{
+		"# TYPE test_results_total counter\n",
+		`test_results_total{check="wss",status="pass"} 2` + "\n",
+		`test_results_total{check="wss",status="fa\"il"} 1` + "\n",
+		`test_duration_seconds_bucket{check="wss",le="0.5"} 1` + "\n",
+		`test_duration_seconds_bucket{check="wss",le="1"} 2` + "\n",
+		`test_duration_seconds_bucket{check="wss",le="+Inf"} 2` + "\n",
+		`test_duration_seconds_count{check="wss"} 2` + "\n",
+		"test_queue_depth 7\n",
+		"go_goroutines ",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestDuplicateRegistrationReturnsError(t *testing.T) {
+	dup := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_results_total", Help: "Results by status."}, []string{"check", "status"})
+	if err := Registry.Register(dup); err == nil {
+		t.Fatal("expected registering a duplicate metric name to fail")
+	}
+}
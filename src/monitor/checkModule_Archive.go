@@ -0,0 +1,159 @@
+package monitor
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+
+	cfg "github.com/ibp-network/ibp-geodns-libs/config"
+	log "github.com/ibp-network/ibp-geodns-libs/logging"
+)
+
+func init() {
+	// Archive check proves historical state is still queryable, not just block hashes
+	RegisterEndpointCheckWithTypes("archive", ArchiveCheck, []string{"RPC"})
+}
+
+// systemNumberKey is the storage key of System::Number, present at every block.
+const systemNumberKey = "0x26aa394eea5630e07c48ae0c9558cef702a5c1b19ab7a04f536c519aca4983ac"
+
+// errStatePruned marks historical queries that failed because the node no
+// longer holds the state of that block.
+var errStatePruned = errors.New("state pruned")
+
+// prunedStateMarkers are fragments of the errors Substrate clients return when
+// asked for discarded state.
+var prunedStateMarkers = []string{"state already discarded", "pruned", "unknown block", "state not available"}
+
+func ArchiveCheck(ctx context.Context, check cfg.Check, endpoint string, service cfg.Service, member cfg.Member) {
+	target, err := parseCheckTarget(endpoint, "wss")
+	if err != nil {
+		UpdateEndpointResultLocal(check, member, service, endpoint, false, fmt.Sprintf("Invalid WebSocket target: %v", err), nil, false)
+		return
+	}
+	target.Scheme = websocketSchemeForTarget(target.Scheme)
+	target.URL = buildTargetURL(target.Scheme, target.Hostname, target.Port, target.RequestURI)
+
+	ip4 := member.Service.ServiceIPv4
+	ip6 := member.Service.ServiceIPv6
+	if ip4 == "" && ip6 == "" {
+		UpdateEndpointResultLocal(check, member, service, endpoint, false, "No IPv4 or IPv6 configured", nil, false)
+		return
+	}
+
+	if ip4 != "" {
+		runArchiveSingle(ctx, check, endpoint, target, service, member, ip4, false)
+	}
+	if ip6 != "" {
+		runArchiveSingle(ctx, check, endpoint, target, service, member, ip6, true)
+	}
+}
+
+func runArchiveSingle(ctx context.Context, check cfg.Check, endpoint string, target CheckTarget, service cfg.Service, member cfg.Member, ip string, isIPv6 bool) {
+	c, err := dialWebsocket(ctx, check, target, ip)
+	if err != nil {
+		UpdateEndpointResultLocal(check, member, service, endpoint, false,
+			checkFailureText(ctx, check, fmt.Sprintf("Failed to connect on IP=%s => %v", ip, err)), nil, isIPv6)
+		return
+	}
+	defer c.Close()
+
+	stopClose := context.AfterFunc(ctx, func() { _ = c.Close() })
+	defer stopClose()
+
+	call := wsSubstrateCall(c, getIntOption(check.ExtraOptions, "ReadTimeout", 15))
+	samples := max(getIntOption(check.ExtraOptions, "Samples", 3), 1)
+	minDepth := int64(getIntOption(check.ExtraOptions, "MinDepth", 1000))
+
+	blocks, err := checkArchiveDepth(call, samples, minDepth, rand.Int63n)
+
+	dataMap := map[string]interface{}{"Samples": blocks}
+	success := err == nil
+	errText := ""
+	if err != nil {
+		errText = checkFailureText(ctx, check, fmt.Sprintf("Archive check failed: %v", err))
+	}
+
+	UpdateEndpointResultLocal(check, member, service, endpoint, success, errText, dataMap, isIPv6)
+	log.Log(log.Debug, "Archive check completed for %s %s isIPv6=%v success=%v", member.Details.Name, endpoint, isIPv6, success)
+}
+
+// checkArchiveDepth queries runtime version and storage at samples random
+// blocks at least minDepth below the best block. randN(n) returns a value in
+// [0, n). It returns what was verified for each block, stopping at the first
+// failure; pruned state is reported as errStatePruned.
+func checkArchiveDepth(call substrateCall, samples int, minDepth int64, randN func(int64) int64) ([]map[string]interface{}, error) {
+	best, err := substrateHeaderNumber(call, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// Genesis is covered by checkFullArchive; sample from block 1 upwards.
+	highest := best - minDepth
+	if highest < 1 {
+		return nil, fmt.Errorf("chain at height %d is shallower than MinDepth %d", best, minDepth)
+	}
+
+	out := make([]map[string]interface{}, 0, samples)
+	for i := 0; i < samples; i++ {
+		number := 1 + randN(highest)
+		entry := map[string]interface{}{"Block": number}
+		out = append(out, entry)
+
+		var hash string
+		desc := fmt.Sprintf("chain_getBlockHash(%d)", number)
+		if err := call("chain_getBlockHash", []interface{}{number}, desc, &hash); err != nil {
+			return out, classifyArchiveError(err)
+		}
+		if hash == "" {
+			return out, fmt.Errorf("%w: %s returned no hash", errStatePruned, desc)
+		}
+		entry["Hash"] = hash
+
+		var runtime struct {
+			SpecVersion int64 `json:"specVersion"`
+		}
+		desc = fmt.Sprintf("state_getRuntimeVersion(%d)", number)
+		if err := call("state_getRuntimeVersion", []interface{}{hash}, desc, &runtime); err != nil {
+			return out, classifyArchiveError(err)
+		}
+		entry["SpecVersion"] = runtime.SpecVersion
+
+		var storage *string
+		desc = fmt.Sprintf("state_getStorage(System.Number, %d)", number)
+		if err := call("state_getStorage", []interface{}{systemNumberKey, hash}, desc, &storage); err != nil {
+			return out, classifyArchiveError(err)
+		}
+		if storage == nil || *storage == "" {
+			return out, fmt.Errorf("%w: %s returned no value", errStatePruned, desc)
+		}
+		if stored, ok := decodeU32LE(*storage); ok {
+			entry["StoredNumber"] = stored
+		}
+	}
+	return out, nil
+}
+
+// classifyArchiveError wraps err with errStatePruned when the node reports
+// that the requested state is gone.
+func classifyArchiveError(err error) error {
+	lower := strings.ToLower(err.Error())
+	for _, marker := range prunedStateMarkers {
+		if strings.Contains(lower, marker) {
+			return fmt.Errorf("%w: %v", errStatePruned, err)
+		}
+	}
+	return err
+}
+
+func decodeU32LE(raw string) (int64, bool) {
+	b, err := hex.DecodeString(strings.TrimPrefix(raw, "0x"))
+	if err != nil || len(b) != 4 {
+		return 0, false
+	}
+	return int64(binary.LittleEndian.Uint32(b)), true
+}
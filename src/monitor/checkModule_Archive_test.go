@@ -0,0 +1,67 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// fakeArchiveCall serves a chain at height best whose state below prunedBelow is gone.
+func fakeArchiveCall(best, prunedBelow int64) substrateCall {
+	return func(method string, params []interface{}, desc string, target interface{}) error {
+		switch method {
+		case "chain_getHeader":
+			*(target.(*map[string]interface{})) = map[string]interface{}{"number": fmt.Sprintf("0x%x", best)}
+		case "chain_getBlockHash":
+			*(target.(*string)) = fmt.Sprintf("0x%064x", params[0].(int64))
+		case "state_getRuntimeVersion", "state_getStorage":
+			var number int64
+			fmt.Sscanf(params[len(params)-1].(string), "0x%x", &number)
+			if number < prunedBelow {
+				return fmt.Errorf("%s: rpc error 4003: State already discarded for %s", desc, params[len(params)-1])
+			}
+			if method == "state_getStorage" {
+				value := fmt.Sprintf("0x%02x%02x%02x%02x", number&0xff, number>>8&0xff, number>>16&0xff, number>>24&0xff)
+				*(target.(**string)) = &value
+				return nil
+			}
+			target.(*struct {
+				SpecVersion int64 `json:"specVersion"`
+			}).SpecVersion = 1003000
+		}
+		return nil
+	}
+}
+
+func TestCheckArchiveDepthVerifiesHistoricalState(t *testing.T) {
+	picks := []int64{0, 4999, 2500}
+	randN := func(n int64) int64 {
+		v := picks[0]
+		picks = picks[1:]
+		return v
+	}
+
+	blocks, err := checkArchiveDepth(fakeArchiveCall(6000, 0), 3, 1000, randN)
+	if err != nil {
+		t.Fatalf("checkArchiveDepth returned error: %v", err)
+	}
+	if len(blocks) != 3 || blocks[0]["Block"] != int64(1) || blocks[1]["Block"] != int64(5000) {
+		t.Fatalf("unexpected samples %#v", blocks)
+	}
+	if blocks[2]["StoredNumber"] != int64(2501) || blocks[2]["SpecVersion"] != int64(1003000) {
+		t.Fatalf("unexpected sample data %#v", blocks[2])
+	}
+}
+
+func TestCheckArchiveDepthReportsPrunedState(t *testing.T) {
+	_, err := checkArchiveDepth(fakeArchiveCall(6000, 4000), 1, 1000, func(int64) int64 { return 10 })
+	if !errors.Is(err, errStatePruned) {
+		t.Fatalf("expected state pruned error, got %v", err)
+	}
+}
+
+func TestCheckArchiveDepthRejectsShallowChain(t *testing.T) {
+	if _, err := checkArchiveDepth(fakeArchiveCall(500, 0), 1, 1000, func(int64) int64 { return 0 }); err == nil {
+		t.Fatalf("expected error for chain shallower than MinDepth")
+	}
+}
@@ -0,0 +1,431 @@
+package monitor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	cfg "github.com/ibp-network/ibp-geodns-libs/config"
+	log "github.com/ibp-network/ibp-geodns-libs/logging"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func init() {
+	// DNS check verifies what each service domain resolves to on the configured resolvers
+	RegisterDomainCheckWithTypes("dns", DnsCheck, []string{"RPC", "ETHRPC"})
+}
+
+var defaultDnsResolvers = []string{"1.1.1.1:53", "8.8.8.8:53"}
+
+// dnsAnswer is the outcome of one A or AAAA query against one resolver.
+type dnsAnswer struct {
+	Addrs []netip.Addr
+	TTL   uint32 // lowest TTL in the answer set
+	RTT   time.Duration
+}
+
+func DnsCheck(ctx context.Context, check cfg.Check, domain string, service cfg.Service, member cfg.Member) {
+	target, err := parseCheckTarget(domain, "https")
+	if err != nil {
+		UpdateDomainResultLocal(check, domain, service, member, false,
+			fmt.Sprintf("Invalid DNS target: %v", err), nil, false)
+		return
+	}
+
+	ip4 := member.Service.ServiceIPv4
+	ip6 := member.Service.ServiceIPv6
+	if ip4 == "" && ip6 == "" {
+		UpdateDomainResultLocal(check, target.Hostname, service, member, false,
+			"No IPv4 or IPv6 configured", nil, false)
+		return
+	}
+
+	resolvers := dnsResolvers(check.ExtraOptions)
+	known := knownMemberAddrs(cfg.GetConfig())
+
+	if ip4 != "" {
+		runDnsSingle(ctx, check, target.Hostname, service, member, ip4, false, resolvers, known)
+	}
+	if ip6 != "" {
+		runDnsSingle(ctx, check, target.Hostname, service, member, ip6, true, resolvers, known)
+	}
+}
+
+func runDnsSingle(
+	ctx context.Context,
+	check cfg.Check,
+	domain string,
+	service cfg.Service,
+	member cfg.Member,
+	ip string,
+	isIPv6 bool,
+	resolvers []string,
+	known map[netip.Addr]string,
+) {
+	memberAddr, err := netip.ParseAddr(ip)
+	if err != nil {
+		UpdateDomainResultLocal(check, domain, service, member, false,
+			fmt.Sprintf("Invalid member IP %q: %v", ip, err), nil, isIPv6)
+		return
+	}
+	memberAddr = memberAddr.Unmap()
+
+	qtype, record := dnsmessage.TypeA, "A"
+	if isIPv6 {
+		qtype, record = dnsmessage.TypeAAAA, "AAAA"
+	}
+
+	timeout := time.Duration(getIntOption(check.ExtraOptions, "QueryTimeout", 5)) * time.Second
+	rejectUnknown := getIntOption(check.ExtraOptions, "RejectUnknown", 1) == 1
+
+	// The member's own address is informational only: GeoDNS answers another
+	// member to most resolvers, and a member marked offline is dropped from
+	// the answers, so requiring it would keep that member offline for good.
+	results, failures, found := queryDnsServers(ctx, resolvers, domain, qtype, netip.Prefix{}, timeout, memberAddr, known, rejectUnknown)
+	dataMap := map[string]interface{}{
+		"Record":        record,
+		"MemberIP":      ip,
+		"MemberIPFound": found,
+		"Resolvers":     results,
+	}
+
+	// Authoritative servers are asked on behalf of the member's own subnet,
+	// which GeoDNS should normally answer with the member itself.
+	if servers := authoritativeServers(check.ExtraOptions); len(servers) > 0 {
+		subnet := clientSubnet(memberAddr)
+		authResults, authFailures, served := queryDnsServers(ctx, servers, domain, qtype, subnet, timeout, memberAddr, known, rejectUnknown)
+		failures = append(failures, authFailures...)
+		dataMap["ClientSubnet"] = subnet.String()
+		dataMap["ServedToOwnSubnet"] = served
+		dataMap["Authoritative"] = authResults
+	}
+
+	success := len(failures) == 0
+	errText := ""
+	if !success {
+		errText = checkFailureText(ctx, check, "DNS check failed: "+strings.Join(failures, "; "))
+	}
+
+	UpdateDomainResultLocal(check, domain, service, member, success, errText, dataMap, isIPv6)
+	log.Log(log.Debug, "DNS check completed for %s %s %s success=%v", member.Details.Name, domain, record, success)
+}
+
+// queryDnsServers queries domain on every server, sending subnet as the EDNS
+// client subnet unless it is the zero Prefix. It returns each server's answer
+// for Data, the failures (errors, NXDOMAIN/SERVFAIL, empty answers and, with
+// rejectUnknown, addresses of no configured member) and whether any answer
+// held memberAddr.
+func queryDnsServers(
+	ctx context.Context,
+	servers []string,
+	domain string,
+	qtype dnsmessage.Type,
+	subnet netip.Prefix,
+	timeout time.Duration,
+	memberAddr netip.Addr,
+	known map[netip.Addr]string,
+	rejectUnknown bool,
+) ([]map[string]interface{}, []string, bool) {
+	var (
+		failures []string
+		anyFound bool
+		results  = make([]map[string]interface{}, 0, len(servers))
+	)
+	for _, server := range servers {
+		entry := map[string]interface{}{"Resolver": server}
+		results = append(results, entry)
+
+		answer, err := queryDns(ctx, server, domain, qtype, subnet, timeout)
+		if err != nil {
+			entry["Error"] = err.Error()
+			failures = append(failures, fmt.Sprintf("%s: %v", server, err))
+			continue
+		}
+
+		found, unknown := evaluateDnsAnswer(answer.Addrs, memberAddr, known)
+		addrs := make([]string, 0, len(answer.Addrs))
+		for _, a := range answer.Addrs {
+			addrs = append(addrs, a.String())
+		}
+		entry["Addresses"] = addrs
+		entry["TTL"] = answer.TTL
+		entry["ResponseMs"] = answer.RTT.Milliseconds()
+		entry["MemberIPFound"] = found
+		anyFound = anyFound || found
+
+		if len(unknown) > 0 {
+			entry["UnknownAddresses"] = unknown
+			if rejectUnknown {
+				failures = append(failures, fmt.Sprintf("%s: unknown addresses %s", server, strings.Join(unknown, ",")))
+			}
+		}
+	}
+	return results, failures, anyFound
+}
+
+// clientSubnet is the EDNS client subnet sent for addr: its /24, or /56 for IPv6.
+func clientSubnet(addr netip.Addr) netip.Prefix {
+	bits := 24
+	if addr.Is6() {
+		bits = 56
+	}
+	prefix, _ := addr.Prefix(bits)
+	return prefix
+}
+
+// evaluateDnsAnswer reports whether memberAddr is in addrs and lists the
+// addresses that belong to no configured member.
+func evaluateDnsAnswer(addrs []netip.Addr, memberAddr netip.Addr, known map[netip.Addr]string) (bool, []string) {
+	found := false
+	var unknown []string
+	for _, a := range addrs {
+		if a == memberAddr {
+			found = true
+			continue
+		}
+		if _, ok := known[a]; !ok {
+			unknown = append(unknown, a.String())
+		}
+	}
+	return found, unknown
+}
+
+// knownMemberAddrs maps every configured member service IP to its member, so
+// GeoDNS answers pointing at another member are not mistaken for hijacks.
+func knownMemberAddrs(c cfg.Config) map[netip.Addr]string {
+	out := make(map[netip.Addr]string)
+	for _, m := range c.Members {
+		for _, ip := range []string{m.Service.ServiceIPv4, m.Service.ServiceIPv6} {
+			if a, err := netip.ParseAddr(ip); err == nil {
+				out[a.Unmap()] = m.Details.Name
+			}
+		}
+	}
+	return out
+}
+
+func authoritativeServers(extraOptions map[string]interface{}) []string {
+	var out []string
+	for _, r := range getStringListOption(extraOptions, "AuthoritativeServers") {
+		out = append(out, normalizeResolver(r))
+	}
+	return out
+}
+
+func dnsResolvers(extraOptions map[string]interface{}) []string {
+	var out []string
+	for _, r := range getStringListOption(extraOptions, "Resolvers") {
+		out = append(out, normalizeResolver(r))
+	}
+	if len(out) == 0 {
+		return defaultDnsResolvers
+	}
+	return out
+}
+
+// normalizeResolver adds the default DNS port when the resolver has none.
+func normalizeResolver(resolver string) string {
+	if _, _, err := net.SplitHostPort(resolver); err == nil {
+		return resolver
+	}
+	return net.JoinHostPort(strings.Trim(resolver, "[]"), "53")
+}
+
+// queryDns sends one query over UDP to resolver, retrying over TCP when the
+// answer is truncated. A valid subnet is sent as the EDNS client subnet.
+func queryDns(ctx context.Context, resolver, name string, qtype dnsmessage.Type, subnet netip.Prefix, timeout time.Duration) (dnsAnswer, error) {
+	fqdn, err := dnsmessage.NewName(strings.TrimSuffix(name, ".") + ".")
+	if err != nil {
+		return dnsAnswer{}, fmt.Errorf("invalid name: %v", err)
+	}
+	var idBytes [2]byte
+	if _, err := rand.Read(idBytes[:]); err != nil {
+		return dnsAnswer{}, fmt.Errorf("query id: %v", err)
+	}
+	id := binary.BigEndian.Uint16(idBytes[:])
+	query, err := buildDnsQuery(id, fqdn, qtype, subnet)
+	if err != nil {
+		return dnsAnswer{}, err
+	}
+
+	start := time.Now()
+	resp, err := exchangeDns(ctx, "udp", resolver, query, timeout)
+	if err != nil {
+		return dnsAnswer{}, err
+	}
+	answer, truncated, err := parseDnsResponse(resp, id, qtype)
+	if err == nil && truncated {
+		if resp, err = exchangeDns(ctx, "tcp", resolver, query, timeout); err != nil {
+			return dnsAnswer{}, err
+		}
+		answer, _, err = parseDnsResponse(resp, id, qtype)
+	}
+	if err != nil {
+		return dnsAnswer{}, err
+	}
+	answer.RTT = time.Since(start)
+	return answer, nil
+}
+
+func buildDnsQuery(id uint16, name dnsmessage.Name, qtype dnsmessage.Type, subnet netip.Prefix) ([]byte, error) {
+	b := dnsmessage.NewBuilder(make([]byte, 0, 512), dnsmessage.Header{ID: id, RecursionDesired: true})
+	b.EnableCompression()
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := b.Question(dnsmessage.Question{Name: name, Type: qtype, Class: dnsmessage.ClassINET}); err != nil {
+		return nil, err
+	}
+	if subnet.IsValid() {
+		if err := b.StartAdditionals(); err != nil {
+			return nil, err
+		}
+		var h dnsmessage.ResourceHeader
+		if err := h.SetEDNS0(1232, dnsmessage.RCodeSuccess, false); err != nil {
+			return nil, err
+		}
+		opt := dnsmessage.Option{Code: ednsClientSubnet, Data: clientSubnetOption(subnet)}
+		if err := b.OPTResource(h, dnsmessage.OPTResource{Options: []dnsmessage.Option{opt}}); err != nil {
+			return nil, err
+		}
+	}
+	return b.Finish()
+}
+
+const ednsClientSubnet = 8 // RFC 7871 option code
+
+// clientSubnetOption encodes subnet as RFC 7871 option data: family, source
+// prefix length, scope prefix length and the significant address bytes.
+func clientSubnetOption(subnet netip.Prefix) []byte {
+	family := uint16(1)
+	if subnet.Addr().Is6() {
+		family = 2
+	}
+	bits := subnet.Bits()
+	addr := subnet.Masked().Addr().AsSlice()
+
+	data := make([]byte, 4, 4+(bits+7)/8)
+	binary.BigEndian.PutUint16(data, family)
+	data[2] = byte(bits)
+	return append(data, addr[:(bits+7)/8]...)
+}
+
+func exchangeDns(ctx context.Context, network, resolver string, query []byte, timeout time.Duration) ([]byte, error) {
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, network, resolver)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	stopClose := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stopClose()
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+
+	if network == "tcp" {
+		framed := make([]byte, 2+len(query))
+		binary.BigEndian.PutUint16(framed, uint16(len(query)))
+		copy(framed[2:], query)
+		if _, err := conn.Write(framed); err != nil {
+			return nil, err
+		}
+		var size [2]byte
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			return nil, err
+		}
+		resp := make([]byte, binary.BigEndian.Uint16(size[:]))
+		if _, err := io.ReadFull(conn, resp); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	// Ignore datagrams that do not answer this query, such as late replies to
+	// an earlier one or spoofing attempts, until the deadline.
+	buf := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		if n >= 2 && binary.BigEndian.Uint16(buf[:2]) == binary.BigEndian.Uint16(query[:2]) {
+			return buf[:n], nil
+		}
+	}
+}
+
+// parseDnsResponse extracts the qtype addresses and the lowest TTL among them.
+// NXDOMAIN and other error rcodes are returned as errors.
+func parseDnsResponse(msg []byte, id uint16, qtype dnsmessage.Type) (dnsAnswer, bool, error) {
+	var p dnsmessage.Parser
+	hdr, err := p.Start(msg)
+	if err != nil {
+		return dnsAnswer{}, false, fmt.Errorf("invalid response: %v", err)
+	}
+	if hdr.ID != id || !hdr.Response {
+		return dnsAnswer{}, false, fmt.Errorf("unexpected response id %d", hdr.ID)
+	}
+	if hdr.Truncated {
+		return dnsAnswer{}, true, nil
+	}
+	if hdr.RCode != dnsmessage.RCodeSuccess {
+		return dnsAnswer{}, false, fmt.Errorf("rcode %s", strings.TrimPrefix(hdr.RCode.String(), "RCode"))
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return dnsAnswer{}, false, fmt.Errorf("invalid response: %v", err)
+	}
+
+	var answer dnsAnswer
+	for {
+		h, err := p.AnswerHeader()
+		if err == dnsmessage.ErrSectionDone {
+			break
+		}
+		if err != nil {
+			return dnsAnswer{}, false, fmt.Errorf("invalid answer: %v", err)
+		}
+		if h.Type != qtype {
+			// CNAMEs in the chain are followed by the resolver; only the final records matter here.
+			if err := p.SkipAnswer(); err != nil {
+				return dnsAnswer{}, false, fmt.Errorf("invalid answer: %v", err)
+			}
+			continue
+		}
+
+		var addr netip.Addr
+		switch qtype {
+		case dnsmessage.TypeA:
+			r, err := p.AResource()
+			if err != nil {
+				return dnsAnswer{}, false, fmt.Errorf("invalid A record: %v", err)
+			}
+			addr = netip.AddrFrom4(r.A)
+		case dnsmessage.TypeAAAA:
+			r, err := p.AAAAResource()
+			if err != nil {
+				return dnsAnswer{}, false, fmt.Errorf("invalid AAAA record: %v", err)
+			}
+			addr = netip.AddrFrom16(r.AAAA).Unmap()
+		}
+
+		if len(answer.Addrs) == 0 || h.TTL < answer.TTL {
+			answer.TTL = h.TTL
+		}
+		answer.Addrs = append(answer.Addrs, addr)
+	}
+
+	if len(answer.Addrs) == 0 {
+		return dnsAnswer{}, false, fmt.Errorf("no %s records", strings.TrimPrefix(qtype.String(), "Type"))
+	}
+	return answer, false, nil
+}
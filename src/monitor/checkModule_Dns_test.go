@@ -0,0 +1,191 @@
+package monitor
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// serveDnsOnce answers the next A query on a local UDP socket with addrs.
+func serveDnsOnce(t *testing.T, addrs []string, ttl uint32) string {
+	t.Helper()
+	return serveDns(t, addrs, ttl, false)
+}
+
+// serveDns is serveDnsOnce; with stray set, a reply with the wrong ID is sent
+// before the real one.
+func serveDns(t *testing.T, addrs []string, ttl uint32, stray bool) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		var p dnsmessage.Parser
+		hdr, err := p.Start(buf[:n])
+		if err != nil {
+			return
+		}
+		q, err := p.Question()
+		if err != nil {
+			return
+		}
+
+		if stray {
+			b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: hdr.ID + 1, Response: true})
+			_ = b.StartQuestions()
+			_ = b.Question(q)
+			if resp, err := b.Finish(); err == nil {
+				_, _ = conn.WriteTo(resp, from)
+			}
+		}
+
+		b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: hdr.ID, Response: true, Authoritative: true})
+		_ = b.StartQuestions()
+		_ = b.Question(q)
+		_ = b.StartAnswers()
+		for _, a := range addrs {
+			ip := netip.MustParseAddr(a).As4()
+			_ = b.AResource(dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: ttl}, dnsmessage.AResource{A: ip})
+		}
+		resp, err := b.Finish()
+		if err != nil {
+			return
+		}
+		_, _ = conn.WriteTo(resp, from)
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func TestQueryDNSReturnsAddressesAndTTL(t *testing.T) {
+	resolver := serveDnsOnce(t, []string{"192.0.2.10", "192.0.2.20"}, 300)
+
+	answer, err := queryDns(context.Background(), resolver, "rpc.example.net", dnsmessage.TypeA, netip.Prefix{}, 2*time.Second)
+	if err != nil {
+		t.Fatalf("queryDns returned error: %v", err)
+	}
+	if len(answer.Addrs) != 2 || answer.Addrs[0] != netip.MustParseAddr("192.0.2.10") {
+		t.Fatalf("unexpected addresses %v", answer.Addrs)
+	}
+	if answer.TTL != 300 {
+		t.Fatalf("expected TTL 300, got %d", answer.TTL)
+	}
+}
+
+func TestQueryDNSSkipsRepliesWithOtherIDs(t *testing.T) {
+	resolver := serveDns(t, []string{"192.0.2.10"}, 300, true)
+
+	answer, err := queryDns(context.Background(), resolver, "rpc.example.net", dnsmessage.TypeA, netip.Prefix{}, 2*time.Second)
+	if err != nil {
+		t.Fatalf("queryDns returned error: %v", err)
+	}
+	if len(answer.Addrs) != 1 || answer.Addrs[0] != netip.MustParseAddr("192.0.2.10") {
+		t.Fatalf("unexpected answer %#v", answer)
+	}
+}
+
+func TestQueryDNSFailsOnEmptyAnswer(t *testing.T) {
+	resolver := serveDnsOnce(t, nil, 300)
+
+	if _, err := queryDns(context.Background(), resolver, "rpc.example.net", dnsmessage.TypeA, netip.Prefix{}, 2*time.Second); err == nil {
+		t.Fatalf("expected an error for an empty answer")
+	}
+}
+
+func TestQueryDnsServersTreatsMissingMemberIPAsInformational(t *testing.T) {
+	member := netip.MustParseAddr("192.0.2.10")
+	known := map[netip.Addr]string{
+		member:                            "alpha",
+		netip.MustParseAddr("192.0.2.20"): "beta",
+	}
+	resolver := serveDnsOnce(t, []string{"192.0.2.20"}, 300)
+
+	results, failures, found := queryDnsServers(context.Background(), []string{resolver}, "rpc.example.net",
+		dnsmessage.TypeA, netip.Prefix{}, 2*time.Second, member, known, true)
+	if len(failures) != 0 {
+		t.Fatalf("expected another member's address to pass, got %v", failures)
+	}
+	if found || results[0]["MemberIPFound"] != false {
+		t.Fatalf("expected the member IP to be reported as not found, got %v", results[0])
+	}
+}
+
+func TestBuildDnsQueryAddsClientSubnet(t *testing.T) {
+	name := dnsmessage.MustNewName("rpc.example.net.")
+	subnet := clientSubnet(netip.MustParseAddr("192.0.2.10"))
+	query, err := buildDnsQuery(1, name, dnsmessage.TypeA, subnet)
+	if err != nil {
+		t.Fatalf("buildDnsQuery returned error: %v", err)
+	}
+
+	var p dnsmessage.Parser
+	if _, err := p.Start(query); err != nil {
+		t.Fatalf("parse query: %v", err)
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		t.Fatalf("skip questions: %v", err)
+	}
+	if err := p.SkipAllAnswers(); err != nil {
+		t.Fatalf("skip answers: %v", err)
+	}
+	if err := p.SkipAllAuthorities(); err != nil {
+		t.Fatalf("skip authorities: %v", err)
+	}
+	if _, err := p.AdditionalHeader(); err != nil {
+		t.Fatalf("expected an OPT record: %v", err)
+	}
+	opt, err := p.OPTResource()
+	if err != nil {
+		t.Fatalf("parse OPT: %v", err)
+	}
+	want := []byte{0, 1, 24, 0, 192, 0, 2}
+	if len(opt.Options) != 1 || opt.Options[0].Code != ednsClientSubnet || string(opt.Options[0].Data) != string(want) {
+		t.Fatalf("unexpected client subnet option %#v", opt.Options)
+	}
+}
+
+func TestEvaluateDnsAnswerFlagsUnknownAddresses(t *testing.T) {
+	member := netip.MustParseAddr("192.0.2.10")
+	known := map[netip.Addr]string{
+		member:                            "alpha",
+		netip.MustParseAddr("192.0.2.20"): "beta",
+	}
+
+	found, unknown := evaluateDnsAnswer([]netip.Addr{
+		netip.MustParseAddr("192.0.2.20"),
+		netip.MustParseAddr("203.0.113.5"),
+	}, member, known)
+	if found {
+		t.Fatalf("member IP should not be reported as found")
+	}
+	if len(unknown) != 1 || unknown[0] != "203.0.113.5" {
+		t.Fatalf("unexpected unknown addresses %v", unknown)
+	}
+}
+
+func TestNormalizeResolverAddsDefaultPort(t *testing.T) {
+	cases := map[string]string{
+		"1.1.1.1":              "1.1.1.1:53",
+		"ns1.example.net:5353": "ns1.example.net:5353",
+		"2606:4700:4700::1111": "[2606:4700:4700::1111]:53",
+		"[2001:db8::1]":        "[2001:db8::1]:53",
+	}
+	for in, want := range cases {
+		if got := normalizeResolver(in); got != want {
+			t.Fatalf("normalizeResolver(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -3,11 +3,9 @@ package monitor
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
-	"net"
 	"net/http"
 	"strconv"
 	"strings"
@@ -39,7 +37,7 @@ func init() {
 	RegisterEndpointCheckWithTypes("ethrpc", EthrpcCheck, []string{"ETHRPC"})
 }
 
-func EthrpcCheck(check cfg.Check, endpoint string, service cfg.Service, member cfg.Member) {
+func EthrpcCheck(ctx context.Context, check cfg.Check, endpoint string, service cfg.Service, member cfg.Member) {
 	target, err := parseCheckTarget(endpoint, "https")
 	if err != nil {
 		UpdateEndpointResultLocal(check, member, service, endpoint, false, fmt.Sprintf("Invalid HTTP RPC target: %v", err), nil, false)
@@ -57,80 +55,56 @@ func EthrpcCheck(check cfg.Check, endpoint string, service cfg.Service, member c
 	}
 
 	if ip4 != "" {
-		runEthrpcSingle(check, endpoint, target, service, member, ip4, false)
+		runEthrpcSingle(ctx, check, endpoint, target, service, member, ip4, false)
 	}
 	if ip6 != "" {
-		runEthrpcSingle(check, endpoint, target, service, member, ip6, true)
+		runEthrpcSingle(ctx, check, endpoint, target, service, member, ip6, true)
 	}
 }
 
-func runEthrpcSingle(check cfg.Check, endpoint string, target CheckTarget, service cfg.Service, member cfg.Member, ip string, isIPv6 bool) {
+func runEthrpcSingle(ctx context.Context, check cfg.Check, endpoint string, target CheckTarget, service cfg.Service, member cfg.Member, ip string, isIPv6 bool) {
 	log.Log(log.Debug, "ETHRPC check: endpoint=%s => url=%s (connecting via %s) for %s",
 		endpoint, target.URL, ip, member.Details.Name)
 
 	// Create HTTP client with custom transport that redirects to IP
 	timeoutSec := getIntOption(check.ExtraOptions, "ConnectTimeout", 10)
-
-	transport := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			ServerName: target.Hostname,
-		},
-		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-			// addr will be "domain:port", we replace with "ip:port"
-			host, port, err := net.SplitHostPort(addr)
-			if err != nil {
-				port = target.Port
-			}
-
-			log.Log(log.Debug, "ETHRPC dial: intercepting %s:%s => %s:%s", host, port, ip, port)
-
-			dialer := &net.Dialer{
-				Timeout: time.Duration(timeoutSec) * time.Second,
-			}
-			return dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
-		},
-	}
-
-	client := &http.Client{
-		Timeout:   time.Duration(timeoutSec) * time.Second,
-		Transport: transport,
-	}
+	client := newPinnedHTTPClient(target, ip, timeoutSec, "ETHRPC")
 
 	// Test 1: Check eth_chainId
-	chainId, err := ethCall(client, target.URL, "eth_chainId", []interface{}{})
+	chainId, err := ethCall(ctx, client, target.URL, "eth_chainId", []interface{}{})
 	if err != nil {
 		UpdateEndpointResultLocal(check, member, service, endpoint, false,
-			fmt.Sprintf("eth_chainId failed: %v", err), nil, isIPv6)
+			checkFailureText(ctx, check, fmt.Sprintf("eth_chainId failed: %v", err)), nil, isIPv6)
 		log.Log(log.Debug, "ETHRPC check failed for %s %s isIPv6=%v - eth_chainId error: %v",
 			member.Details.Name, endpoint, isIPv6, err)
 		return
 	}
 
 	// Test 2: Check eth_blockNumber
-	blockNumber, err := ethCall(client, target.URL, "eth_blockNumber", []interface{}{})
+	blockNumber, err := ethCall(ctx, client, target.URL, "eth_blockNumber", []interface{}{})
 	if err != nil {
 		UpdateEndpointResultLocal(check, member, service, endpoint, false,
-			fmt.Sprintf("eth_blockNumber failed: %v", err), nil, isIPv6)
+			checkFailureText(ctx, check, fmt.Sprintf("eth_blockNumber failed: %v", err)), nil, isIPv6)
 		log.Log(log.Debug, "ETHRPC check failed for %s %s isIPv6=%v - eth_blockNumber error",
 			member.Details.Name, endpoint, isIPv6)
 		return
 	}
 
 	// Test 3: Check net_version
-	netVersion, err := ethCall(client, target.URL, "net_version", []interface{}{})
+	netVersion, err := ethCall(ctx, client, target.URL, "net_version", []interface{}{})
 	if err != nil {
 		UpdateEndpointResultLocal(check, member, service, endpoint, false,
-			fmt.Sprintf("net_version failed: %v", err), nil, isIPv6)
+			checkFailureText(ctx, check, fmt.Sprintf("net_version failed: %v", err)), nil, isIPv6)
 		log.Log(log.Debug, "ETHRPC check failed for %s %s isIPv6=%v - net_version error",
 			member.Details.Name, endpoint, isIPv6)
 		return
 	}
 
 	// Test 4: Check eth_syncing - must be false
-	syncingResult, err := ethCall(client, target.URL, "eth_syncing", []interface{}{})
+	syncingResult, err := ethCall(ctx, client, target.URL, "eth_syncing", []interface{}{})
 	if err != nil {
 		UpdateEndpointResultLocal(check, member, service, endpoint, false,
-			fmt.Sprintf("eth_syncing failed: %v", err), nil, isIPv6)
+			checkFailureText(ctx, check, fmt.Sprintf("eth_syncing failed: %v", err)), nil, isIPv6)
 		log.Log(log.Debug, "ETHRPC check failed for %s %s isIPv6=%v - eth_syncing error",
 			member.Details.Name, endpoint, isIPv6)
 		return
@@ -201,7 +175,6 @@ func runEthrpcSingle(check cfg.Check, endpoint string, target CheckTarget, servi
 		return
 	}
 
-	// All checks passed
 	dataMap := map[string]interface{}{
 		"chainId":     chainIdStr,
 		"chainIdDec":  chainIdDecimal,
@@ -211,11 +184,203 @@ func runEthrpcSingle(check cfg.Check, endpoint string, target CheckTarget, servi
 		"network":     expectedNetwork,
 	}
 
+	// Client version is informational only
+	if clientVersion, err := ethCall(ctx, client, target.URL, "web3_clientVersion", []interface{}{}); err == nil {
+		var clientVersionStr string
+		if json.Unmarshal(clientVersion, &clientVersionStr) == nil {
+			dataMap["clientVersion"] = clientVersionStr
+		}
+	}
+
+	// Optional: check the latest block is recent - a node can report not syncing while stuck
+	if maxHeadAge := int64(getIntOption(check.ExtraOptions, "MaxHeadAgeSeconds", 0)); maxHeadAge > 0 {
+		headTimestamp, err := fetchEthHeadTimestamp(ctx, client, target.URL)
+		if err != nil {
+			UpdateEndpointResultLocal(check, member, service, endpoint, false,
+				checkFailureText(ctx, check, fmt.Sprintf("eth_getBlockByNumber(latest) failed: %v", err)), dataMap, isIPv6)
+			log.Log(log.Debug, "ETHRPC check failed for %s %s isIPv6=%v - latest block error: %v",
+				member.Details.Name, endpoint, isIPv6, err)
+			return
+		}
+
+		headAge := int64(time.Since(time.Unix(headTimestamp, 0)).Seconds())
+		dataMap["headTimestamp"] = headTimestamp
+		dataMap["headAgeSeconds"] = headAge
+		dataMap["maxHeadAgeSeconds"] = maxHeadAge
+
+		if headAge > maxHeadAge {
+			UpdateEndpointResultLocal(check, member, service, endpoint, false,
+				fmt.Sprintf("Head block is %ds old (MaxHeadAgeSeconds %d)", headAge, maxHeadAge), dataMap, isIPv6)
+			log.Log(log.Debug, "ETHRPC check failed for %s %s isIPv6=%v - stale head %ds",
+				member.Details.Name, endpoint, isIPv6, headAge)
+			return
+		}
+	}
+
+	// Optional capability assertions
+	assertions, failures := runEthAssertions(ctx, client, target.URL, parseEthAssertionOptions(check.ExtraOptions))
+	if len(assertions) > 0 {
+		dataMap["assertions"] = assertions
+	}
+	if len(failures) > 0 {
+		UpdateEndpointResultLocal(check, member, service, endpoint, false,
+			checkFailureText(ctx, check, strings.Join(failures, "; ")), dataMap, isIPv6)
+		log.Log(log.Debug, "ETHRPC check failed for %s %s isIPv6=%v - %s",
+			member.Details.Name, endpoint, isIPv6, strings.Join(failures, "; "))
+		return
+	}
+
+	// All checks passed
 	UpdateEndpointResultLocal(check, member, service, endpoint, true, "", dataMap, isIPv6)
 	log.Log(log.Debug, "ETHRPC check completed for %s %s isIPv6=%v success=%v",
 		member.Details.Name, endpoint, isIPv6, true)
 }
 
+// fetchEthHeadTimestamp returns the unix timestamp of the latest block.
+func fetchEthHeadTimestamp(ctx context.Context, client *http.Client, url string) (int64, error) {
+	raw, err := ethCall(ctx, client, url, "eth_getBlockByNumber", []interface{}{"latest", false})
+	if err != nil {
+		return 0, err
+	}
+	var block struct {
+		Timestamp string `json:"timestamp"`
+	}
+	if err := json.Unmarshal(raw, &block); err != nil {
+		return 0, fmt.Errorf("invalid block: %v", err)
+	}
+	timestamp, ok := parseBlockNumber(block.Timestamp)
+	if !ok {
+		return 0, fmt.Errorf("invalid block timestamp %q", block.Timestamp)
+	}
+	return timestamp, nil
+}
+
+// ethAssertionOptions selects the optional ETHRPC capability assertions.
+type ethAssertionOptions struct {
+	CheckBalance   bool
+	BalanceAddress string
+	CheckLogs      bool
+	LogsAddress    string
+	LogsRange      int64
+	CheckFullBlock bool
+	HistoricBlock  int64
+	MinPeerCount   int64
+}
+
+func parseEthAssertionOptions(extraOptions map[string]interface{}) ethAssertionOptions {
+	return ethAssertionOptions{
+		CheckBalance:   getIntOption(extraOptions, "CheckBalance", 0) == 1,
+		BalanceAddress: getStringOption(extraOptions, "BalanceAddress", "0x0000000000000000000000000000000000000000"),
+		CheckLogs:      getIntOption(extraOptions, "CheckLogs", 0) == 1,
+		LogsAddress:    getStringOption(extraOptions, "LogsAddress", ""),
+		LogsRange:      int64(max(getIntOption(extraOptions, "LogsRange", 100), 1)),
+		CheckFullBlock: getIntOption(extraOptions, "CheckFullBlock", 0) == 1,
+		HistoricBlock:  int64(max(getIntOption(extraOptions, "HistoricBlock", 1), 0)),
+		MinPeerCount:   int64(getIntOption(extraOptions, "MinPeerCount", 0)),
+	}
+}
+
+// runEthAssertions runs the enabled assertions and returns each one's
+// outcome and latency keyed by method, plus the failure reasons.
+func runEthAssertions(ctx context.Context, client *http.Client, url string, opts ethAssertionOptions) (map[string]interface{}, []string) {
+	results := make(map[string]interface{})
+	var failures []string
+
+	run := func(method string, params []interface{}, verify func(json.RawMessage) (map[string]interface{}, error)) {
+		start := time.Now()
+		raw, err := ethCall(ctx, client, url, method, params)
+		entry := map[string]interface{}{"LatencyMs": time.Since(start).Milliseconds()}
+		if err == nil {
+			var extra map[string]interface{}
+			extra, err = verify(raw)
+			for k, v := range extra {
+				entry[k] = v
+			}
+		}
+		entry["OK"] = err == nil
+		if err != nil {
+			entry["Error"] = err.Error()
+			failures = append(failures, fmt.Sprintf("%s failed: %v", method, err))
+		}
+		results[method] = entry
+	}
+
+	historic := fmt.Sprintf("0x%x", opts.HistoricBlock)
+
+	if opts.CheckBalance {
+		run("eth_getBalance", []interface{}{opts.BalanceAddress, historic}, func(raw json.RawMessage) (map[string]interface{}, error) {
+			var balance string
+			if err := json.Unmarshal(raw, &balance); err != nil || !strings.HasPrefix(balance, "0x") {
+				return nil, fmt.Errorf("invalid balance %s", string(raw))
+			}
+			return map[string]interface{}{"Block": opts.HistoricBlock, "Balance": balance}, nil
+		})
+	}
+
+	if opts.CheckLogs {
+		filter := map[string]interface{}{
+			"fromBlock": historic,
+			"toBlock":   fmt.Sprintf("0x%x", opts.HistoricBlock+opts.LogsRange-1),
+		}
+		if opts.LogsAddress != "" {
+			filter["address"] = opts.LogsAddress
+		}
+		run("eth_getLogs", []interface{}{filter}, func(raw json.RawMessage) (map[string]interface{}, error) {
+			var logs []json.RawMessage
+			if err := json.Unmarshal(raw, &logs); err != nil || logs == nil {
+				return nil, fmt.Errorf("invalid logs %s", truncateForError(raw))
+			}
+			return map[string]interface{}{"Block": opts.HistoricBlock, "Range": opts.LogsRange, "Logs": len(logs)}, nil
+		})
+	}
+
+	if opts.CheckFullBlock {
+		run("eth_getBlockByNumber", []interface{}{historic, true}, func(raw json.RawMessage) (map[string]interface{}, error) {
+			var block struct {
+				Hash         string            `json:"hash"`
+				Transactions []json.RawMessage `json:"transactions"`
+			}
+			if err := json.Unmarshal(raw, &block); err != nil || block.Hash == "" {
+				return nil, fmt.Errorf("block %d not available", opts.HistoricBlock)
+			}
+			for _, tx := range block.Transactions {
+				if !strings.HasPrefix(strings.TrimSpace(string(tx)), "{") {
+					return nil, fmt.Errorf("block %d returned transaction hashes instead of full transactions", opts.HistoricBlock)
+				}
+			}
+			return map[string]interface{}{"Block": opts.HistoricBlock, "Transactions": len(block.Transactions)}, nil
+		})
+	}
+
+	if opts.MinPeerCount > 0 {
+		run("net_peerCount", []interface{}{}, func(raw json.RawMessage) (map[string]interface{}, error) {
+			var countStr string
+			if err := json.Unmarshal(raw, &countStr); err != nil {
+				return nil, fmt.Errorf("invalid peer count %s", string(raw))
+			}
+			count, ok := parseBlockNumber(countStr)
+			if !ok {
+				return nil, fmt.Errorf("invalid peer count %q", countStr)
+			}
+			extra := map[string]interface{}{"PeerCount": count, "MinPeerCount": opts.MinPeerCount}
+			if count < opts.MinPeerCount {
+				return extra, fmt.Errorf("%d peers, need %d", count, opts.MinPeerCount)
+			}
+			return extra, nil
+		})
+	}
+
+	return results, failures
+}
+
+func truncateForError(raw json.RawMessage) string {
+	const limit = 200
+	if len(raw) > limit {
+		return string(raw[:limit]) + "..."
+	}
+	return string(raw)
+}
+
 func parseEthSyncing(result json.RawMessage) (bool, error) {
 	payload := strings.TrimSpace(string(result))
 	switch strings.ToLower(payload) {
@@ -289,7 +454,7 @@ func httpSchemeForTarget(scheme string) string {
 	}
 }
 
-func ethCall(client *http.Client, url string, method string, params []interface{}) (json.RawMessage, error) {
+func ethCall(ctx context.Context, client *http.Client, url string, method string, params []interface{}) (json.RawMessage, error) {
 	request := EthRPCRequest{
 		JSONRPC: "2.0",
 		Method:  method,
@@ -302,7 +467,7 @@ func ethCall(client *http.Client, url string, method string, params []interface{
 		return nil, fmt.Errorf("failed to marshal request: %v", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %v", err)
 	}
@@ -0,0 +1,95 @@
+package monitor
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRunEthAssertionsRecordsLatencyAndResults(t *testing.T) {
+	srv := newSubstrateRPCServer(t, map[string]interface{}{
+		"eth_getBalance": "0x1bc16d674ec80000",
+		"eth_getLogs":    []interface{}{map[string]interface{}{"blockNumber": "0x1"}},
+		"eth_getBlockByNumber": map[string]interface{}{
+			"hash":         "0xabc",
+			"transactions": []interface{}{map[string]interface{}{"hash": "0x1"}},
+		},
+		"net_peerCount": "0x19",
+	})
+	defer srv.Close()
+
+	results, failures := runEthAssertions(context.Background(), srv.Client(), srv.URL, ethAssertionOptions{
+		CheckBalance:   true,
+		CheckLogs:      true,
+		LogsRange:      100,
+		CheckFullBlock: true,
+		HistoricBlock:  1,
+		MinPeerCount:   10,
+	})
+	if len(failures) != 0 {
+		t.Fatalf("expected no failures, got %v", failures)
+	}
+	for _, method := range []string{"eth_getBalance", "eth_getLogs", "eth_getBlockByNumber", "net_peerCount"} {
+		entry, ok := results[method].(map[string]interface{})
+		if !ok || entry["OK"] != true {
+			t.Fatalf("expected %s to pass, got %#v", method, results[method])
+		}
+		if _, ok := entry["LatencyMs"]; !ok {
+			t.Fatalf("expected latency for %s", method)
+		}
+	}
+	if results["net_peerCount"].(map[string]interface{})["PeerCount"] != int64(25) {
+		t.Fatalf("unexpected peer count %#v", results["net_peerCount"])
+	}
+}
+
+func TestRunEthAssertionsReportsFailures(t *testing.T) {
+	srv := newSubstrateRPCServer(t, map[string]interface{}{
+		"eth_getBlockByNumber": map[string]interface{}{
+			"hash":         "0xabc",
+			"transactions": []interface{}{"0x1"},
+		},
+		"net_peerCount": "0x2",
+	})
+	defer srv.Close()
+
+	results, failures := runEthAssertions(context.Background(), srv.Client(), srv.URL, ethAssertionOptions{
+		CheckBalance:   true,
+		CheckFullBlock: true,
+		HistoricBlock:  1,
+		MinPeerCount:   10,
+	})
+	joined := strings.Join(failures, "; ")
+	for _, want := range []string{"eth_getBalance failed", "transaction hashes", "2 peers, need 10"} {
+		if !strings.Contains(joined, want) {
+			t.Fatalf("expected failure containing %q, got %q", want, joined)
+		}
+	}
+	if _, ran := results["eth_getLogs"]; ran {
+		t.Fatalf("disabled assertions must not run")
+	}
+}
+
+func TestFetchEthHeadTimestamp(t *testing.T) {
+	srv := newSubstrateRPCServer(t, map[string]interface{}{
+		"eth_getBlockByNumber": map[string]interface{}{"number": "0x10", "timestamp": "0x6553f100"},
+	})
+	defer srv.Close()
+
+	ts, err := fetchEthHeadTimestamp(context.Background(), srv.Client(), srv.URL)
+	if err != nil {
+		t.Fatalf("fetchEthHeadTimestamp returned error: %v", err)
+	}
+	if ts != 0x6553f100 {
+		t.Fatalf("unexpected timestamp %d", ts)
+	}
+}
+
+func TestFetchEthHeadTimestampRejectsMissingBlock(t *testing.T) {
+	srv := newSubstrateRPCServer(t, map[string]interface{}{"eth_getBlockByNumber": nil})
+	defer srv.Close()
+
+	if _, err := fetchEthHeadTimestamp(context.Background(), srv.Client(), srv.URL); err == nil {
+		t.Fatalf("expected error for a null latest block")
+	}
+}
@@ -0,0 +1,244 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	cfg "github.com/ibp-network/ibp-geodns-libs/config"
+	log "github.com/ibp-network/ibp-geodns-libs/logging"
+)
+
+func init() {
+	// Height check compares each member against the best head seen across all members
+	RegisterEndpointCheckWithTypes("height", HeightCheck, []string{"RPC", "ETHRPC"})
+}
+
+type heightObservation struct {
+	Best         int64
+	Finalized    int64
+	HasFinalized bool
+}
+
+var blockHeights = newObservationBoard[heightObservation]()
+
+// networkHeads returns the highest best and finalized heights among obs.
+func networkHeads(obs map[string]heightObservation) (int64, int64) {
+	var bestMax, finalizedMax int64
+	for _, o := range obs {
+		if o.Best > bestMax {
+			bestMax = o.Best
+		}
+		if o.HasFinalized && o.Finalized > finalizedMax {
+			finalizedMax = o.Finalized
+		}
+	}
+	return bestMax, finalizedMax
+}
+
+func HeightCheck(ctx context.Context, check cfg.Check, endpoint string, service cfg.Service, member cfg.Member) {
+	isEth := strings.EqualFold(service.Configuration.ServiceType, "ETHRPC")
+	defaultScheme := "wss"
+	if isEth {
+		defaultScheme = "https"
+	}
+
+	target, err := parseCheckTarget(endpoint, defaultScheme)
+	if err != nil {
+		UpdateEndpointResultLocal(check, member, service, endpoint, false, fmt.Sprintf("Invalid height target: %v", err), nil, false)
+		return
+	}
+	if isEth {
+		target.Scheme = httpSchemeForTarget(target.Scheme)
+	} else {
+		target.Scheme = websocketSchemeForTarget(target.Scheme)
+	}
+	target.URL = buildTargetURL(target.Scheme, target.Hostname, target.Port, target.RequestURI)
+
+	ip4 := member.Service.ServiceIPv4
+	ip6 := member.Service.ServiceIPv6
+
+	if ip4 == "" && ip6 == "" {
+		UpdateEndpointResultLocal(check, member, service, endpoint, false, "No IPv4 or IPv6 configured", nil, false)
+		return
+	}
+
+	if ip4 != "" {
+		runHeightSingle(ctx, check, endpoint, target, service, member, ip4, false, isEth)
+	}
+	if ip6 != "" {
+		runHeightSingle(ctx, check, endpoint, target, service, member, ip6, true, isEth)
+	}
+}
+
+func runHeightSingle(ctx context.Context, check cfg.Check, endpoint string, target CheckTarget, service cfg.Service, member cfg.Member, ip string, isIPv6 bool, isEth bool) {
+	var (
+		obs heightObservation
+		err error
+	)
+	if isEth {
+		obs, err = fetchEthHeight(ctx, check, target, ip)
+	} else {
+		obs, err = fetchSubstrateHeights(ctx, check, target, ip)
+	}
+	if err != nil {
+		UpdateEndpointResultLocal(check, member, service, endpoint, false,
+			checkFailureText(ctx, check, fmt.Sprintf("Height query failed: %v", err)), nil, isIPv6)
+		log.Log(log.Debug, "Height check failed for %s %s isIPv6=%v: %v", member.Details.Name, endpoint, isIPv6, err)
+		return
+	}
+
+	network, source := observationScope(service, member, target, ip)
+	observed := blockHeights.record(network, source, obs, time.Now(), observationWindow(check))
+	bestMax, finalizedMax := networkHeads(observed)
+
+	maxBestLag := int64(getIntOption(check.ExtraOptions, "MaxBestLag", 10))
+	maxFinalizedLag := int64(getIntOption(check.ExtraOptions, "MaxFinalizedLag", 20))
+
+	bestLag := bestMax - obs.Best
+	dataMap := map[string]interface{}{
+		"BestHeight":        obs.Best,
+		"NetworkBestHeight": bestMax,
+		"BestLag":           bestLag,
+		"MaxBestLag":        maxBestLag,
+		"NetworkSources":    observationMembers(observed),
+	}
+
+	var finalizedLag int64
+	if obs.HasFinalized {
+		finalizedLag = finalizedMax - obs.Finalized
+		dataMap["FinalizedHeight"] = obs.Finalized
+		dataMap["NetworkFinalizedHeight"] = finalizedMax
+		dataMap["FinalizedLag"] = finalizedLag
+		dataMap["MaxFinalizedLag"] = maxFinalizedLag
+	}
+
+	errText := heightLagFailure(bestLag, finalizedLag, obs.HasFinalized, maxBestLag, maxFinalizedLag)
+	success := errText == ""
+
+	// Lagging behind another member is a failure however few were observed,
+	// but keeping up with too few members proves nothing.
+	if minSources := observationMinSources(check); success && observationMembers(observed) < minSources {
+		reason := fmt.Sprintf("Network head from %d member(s), MinSources %d", observationMembers(observed), minSources)
+		UpdateEndpointResultInconclusive(check, member, service, endpoint, reason, dataMap, isIPv6)
+		log.Log(log.Debug, "Height check inconclusive for %s %s isIPv6=%v: %s", member.Details.Name, endpoint, isIPv6, reason)
+		return
+	}
+
+	UpdateEndpointResultLocal(check, member, service, endpoint, success, errText, dataMap, isIPv6)
+	log.Log(log.Debug, "Height check completed for %s %s isIPv6=%v success=%v bestLag=%d finalizedLag=%d",
+		member.Details.Name, endpoint, isIPv6, success, bestLag, finalizedLag)
+}
+
+// heightLagFailure returns the failure reason for the observed lags, or an
+// empty string when both are within their limits.
+func heightLagFailure(bestLag, finalizedLag int64, hasFinalized bool, maxBestLag, maxFinalizedLag int64) string {
+	if bestLag > maxBestLag {
+		return fmt.Sprintf("Best block %d behind network head (MaxBestLag %d)", bestLag, maxBestLag)
+	}
+	if hasFinalized && finalizedLag > maxFinalizedLag {
+		return fmt.Sprintf("Finalized block %d behind network head (MaxFinalizedLag %d)", finalizedLag, maxFinalizedLag)
+	}
+	return ""
+}
+
+func fetchSubstrateHeights(ctx context.Context, check cfg.Check, target CheckTarget, ip string) (heightObservation, error) {
+	c, err := dialWebsocket(ctx, check, target, ip)
+	if err != nil {
+		return heightObservation{}, fmt.Errorf("failed to connect on IP=%s => %v", ip, err)
+	}
+	defer c.Close()
+
+	stopClose := context.AfterFunc(ctx, func() { _ = c.Close() })
+	defer stopClose()
+
+	call := wsSubstrateCall(c, getIntOption(check.ExtraOptions, "ReadTimeout", 15))
+
+	best, err := substrateHeaderNumber(call, "")
+	if err != nil {
+		return heightObservation{}, err
+	}
+
+	var finalizedHash string
+	if err := call("chain_getFinalizedHead", nil, "chain_getFinalizedHead", &finalizedHash); err != nil {
+		return heightObservation{}, err
+	}
+	if finalizedHash == "" {
+		return heightObservation{}, fmt.Errorf("chain_getFinalizedHead returned an empty result")
+	}
+
+	finalized, err := substrateHeaderNumber(call, finalizedHash)
+	if err != nil {
+		return heightObservation{}, err
+	}
+
+	return heightObservation{
+		Best:         best,
+		Finalized:    finalized,
+		HasFinalized: true,
+	}, nil
+}
+
+// substrateHeaderNumber reads the block number from chain_getHeader at hash,
+// or at the best block when hash is empty.
+func substrateHeaderNumber(call substrateCall, hash string) (int64, error) {
+	var (
+		params []interface{}
+		desc   = "chain_getHeader(best)"
+	)
+	if hash != "" {
+		params = []interface{}{hash}
+		desc = "chain_getHeader(" + hash + ")"
+	}
+
+	header := make(map[string]interface{})
+	if err := call("chain_getHeader", params, desc, &header); err != nil {
+		return 0, err
+	}
+
+	raw, _ := header["number"].(string)
+	number, ok := parseBlockNumber(raw)
+	if !ok {
+		return 0, fmt.Errorf("%s: invalid block number %v", desc, header["number"])
+	}
+	return number, nil
+}
+
+func fetchEthHeight(ctx context.Context, check cfg.Check, target CheckTarget, ip string) (heightObservation, error) {
+	client := newPinnedHTTPClient(target, ip, getIntOption(check.ExtraOptions, "ConnectTimeout", 10), "Height")
+	defer client.CloseIdleConnections()
+
+	result, err := ethCall(ctx, client, target.URL, "eth_blockNumber", []interface{}{})
+	if err != nil {
+		return heightObservation{}, fmt.Errorf("eth_blockNumber failed: %v", err)
+	}
+
+	var raw string
+	if err := json.Unmarshal(result, &raw); err != nil {
+		return heightObservation{}, fmt.Errorf("invalid eth_blockNumber response: %v", err)
+	}
+	number, ok := parseBlockNumber(raw)
+	if !ok {
+		return heightObservation{}, fmt.Errorf("invalid eth_blockNumber response %q", raw)
+	}
+
+	return heightObservation{Best: number}, nil
+}
+
+// parseBlockNumber accepts the 0x-prefixed hex quantities returned by both
+// Substrate and Ethereum nodes, as well as plain decimals.
+func parseBlockNumber(raw string) (int64, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, false
+	}
+	if strings.HasPrefix(strings.ToLower(raw), "0x") {
+		out, err := strconv.ParseInt(raw[2:], 16, 64)
+		return out, err == nil
+	}
+	out, err := strconv.ParseInt(raw, 10, 64)
+	return out, err == nil
+}
@@ -0,0 +1,41 @@
+package monitor
+
+import "testing"
+
+func TestNetworkHeadsIgnoresFinalizedWithoutFinality(t *testing.T) {
+	best, finalized := networkHeads(map[string]heightObservation{
+		"a": {Best: 1000, Finalized: 998, HasFinalized: true},
+		"b": {Best: 990, Finalized: 970, HasFinalized: true},
+		"c": {Best: 1005, Finalized: 1005},
+	})
+	if best != 1005 || finalized != 998 {
+		t.Fatalf("expected network maximum 1005/998, got %d/%d", best, finalized)
+	}
+}
+
+func TestHeightLagFailure(t *testing.T) {
+	if got := heightLagFailure(10, 20, true, 10, 20); got != "" {
+		t.Fatalf("expected lags at the limit to pass, got %q", got)
+	}
+	if got := heightLagFailure(11, 0, true, 10, 20); got == "" {
+		t.Fatalf("expected best lag over limit to fail")
+	}
+	if got := heightLagFailure(0, 21, true, 10, 20); got == "" {
+		t.Fatalf("expected finalized lag over limit to fail")
+	}
+	if got := heightLagFailure(0, 500, false, 10, 20); got != "" {
+		t.Fatalf("expected finalized lag to be ignored without finality, got %q", got)
+	}
+}
+
+func TestParseBlockNumberSupportsHexAndDecimal(t *testing.T) {
+	if got, ok := parseBlockNumber("0x1a"); !ok || got != 26 {
+		t.Fatalf("expected 0x1a to parse as 26, got %d ok=%v", got, ok)
+	}
+	if got, ok := parseBlockNumber("26"); !ok || got != 26 {
+		t.Fatalf("expected 26 to parse as 26, got %d ok=%v", got, ok)
+	}
+	if _, ok := parseBlockNumber(""); ok {
+		t.Fatalf("expected empty block number to fail parsing")
+	}
+}
@@ -0,0 +1,238 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	cfg "github.com/ibp-network/ibp-geodns-libs/config"
+	log "github.com/ibp-network/ibp-geodns-libs/logging"
+
+	"github.com/tidwall/gjson"
+)
+
+func init() {
+	// HTTP check can run per domain (health routes) or per endpoint URL
+	RegisterDomainCheck("http", HttpDomainCheck)
+	RegisterEndpointCheck("http", HttpEndpointCheck)
+}
+
+// httpAssertions holds the request and expectations read from ExtraOptions.
+type httpAssertions struct {
+	Method       string
+	Path         string
+	Headers      map[string]string
+	Body         string
+	StatusMin    int
+	StatusMax    int
+	Header       string
+	HeaderValue  string
+	BodyRegex    *regexp.Regexp
+	JSONPath     string
+	JSONValue    string
+	HasJSONValue bool
+	MaxBodyBytes int64
+}
+
+func parseHttpAssertions(extraOptions map[string]interface{}) (httpAssertions, error) {
+	a := httpAssertions{
+		Method:       strings.ToUpper(getStringOption(extraOptions, "Method", http.MethodGet)),
+		Path:         getStringOption(extraOptions, "Path", ""),
+		Headers:      getStringMapOption(extraOptions, "Headers"),
+		Body:         getStringOption(extraOptions, "Body", ""),
+		StatusMin:    getIntOption(extraOptions, "ExpectStatusMin", 200),
+		StatusMax:    getIntOption(extraOptions, "ExpectStatusMax", 299),
+		Header:       getStringOption(extraOptions, "ExpectHeader", ""),
+		HeaderValue:  getStringOption(extraOptions, "ExpectHeaderValue", ""),
+		JSONPath:     getStringOption(extraOptions, "JsonPath", ""),
+		MaxBodyBytes: int64(getIntOption(extraOptions, "MaxBodyBytes", 1<<20)),
+	}
+	if v, ok := extraOptions["JsonValue"]; ok && v != nil {
+		a.JSONValue = fmt.Sprint(v)
+		a.HasJSONValue = true
+	}
+	if expr := getStringOption(extraOptions, "BodyRegex", ""); expr != "" {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return httpAssertions{}, fmt.Errorf("invalid BodyRegex: %v", err)
+		}
+		a.BodyRegex = re
+	}
+	return a, nil
+}
+
+func HttpDomainCheck(ctx context.Context, check cfg.Check, domain string, service cfg.Service, member cfg.Member) {
+	report := func(status bool, errText string, data map[string]interface{}, isIPv6 bool) {
+		UpdateDomainResultLocal(check, domain, service, member, status, errText, data, isIPv6)
+	}
+
+	assertions, err := parseHttpAssertions(check.ExtraOptions)
+	if err != nil {
+		report(false, err.Error(), nil, false)
+		return
+	}
+
+	target, err := parseCheckTarget(domain, "https")
+	if err != nil {
+		report(false, fmt.Sprintf("Invalid HTTP target: %v", err), nil, false)
+		return
+	}
+	if assertions.Path == "" {
+		assertions.Path = "/"
+	}
+
+	runHttpCheck(ctx, check, target, member, assertions, report)
+}
+
+func HttpEndpointCheck(ctx context.Context, check cfg.Check, endpoint string, service cfg.Service, member cfg.Member) {
+	report := func(status bool, errText string, data map[string]interface{}, isIPv6 bool) {
+		UpdateEndpointResultLocal(check, member, service, endpoint, status, errText, data, isIPv6)
+	}
+
+	assertions, err := parseHttpAssertions(check.ExtraOptions)
+	if err != nil {
+		report(false, err.Error(), nil, false)
+		return
+	}
+
+	target, err := parseCheckTarget(endpoint, "https")
+	if err != nil {
+		report(false, fmt.Sprintf("Invalid HTTP target: %v", err), nil, false)
+		return
+	}
+	if assertions.Path == "" {
+		assertions.Path = target.RequestURI
+	}
+
+	runHttpCheck(ctx, check, target, member, assertions, report)
+}
+
+func runHttpCheck(ctx context.Context, check cfg.Check, target CheckTarget, member cfg.Member, assertions httpAssertions,
+	report func(status bool, errText string, data map[string]interface{}, isIPv6 bool)) {
+	target.Scheme = httpSchemeForTarget(target.Scheme)
+	target.RequestURI = assertions.Path
+	if !strings.HasPrefix(target.RequestURI, "/") {
+		target.RequestURI = "/" + target.RequestURI
+	}
+	target.URL = buildTargetURL(target.Scheme, target.Hostname, target.Port, target.RequestURI)
+
+	ip4 := member.Service.ServiceIPv4
+	ip6 := member.Service.ServiceIPv6
+	if ip4 == "" && ip6 == "" {
+		report(false, "No IPv4 or IPv6 configured", nil, false)
+		return
+	}
+
+	if ip4 != "" {
+		runHttpSingle(ctx, check, target, member, ip4, false, assertions, report)
+	}
+	if ip6 != "" {
+		runHttpSingle(ctx, check, target, member, ip6, true, assertions, report)
+	}
+}
+
+func runHttpSingle(ctx context.Context, check cfg.Check, target CheckTarget, member cfg.Member, ip string, isIPv6 bool,
+	assertions httpAssertions, report func(status bool, errText string, data map[string]interface{}, isIPv6 bool)) {
+	client := newPinnedHTTPClient(target, ip, getIntOption(check.ExtraOptions, "ConnectTimeout", 10), "HTTP")
+	defer client.CloseIdleConnections()
+
+	var body io.Reader
+	if assertions.Body != "" {
+		body = strings.NewReader(assertions.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, assertions.Method, target.URL, body)
+	if err != nil {
+		report(false, fmt.Sprintf("Failed to build request: %v", err), nil, isIPv6)
+		return
+	}
+	for k, v := range assertions.Headers {
+		req.Header.Set(k, v)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		report(false, checkFailureText(ctx, check, fmt.Sprintf("Request failed on IP=%s => %v", ip, err)), nil, isIPv6)
+		log.Log(log.Debug, "HTTP check failed for %s %s isIPv6=%v: %v", member.Details.Name, target.URL, isIPv6, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, assertions.MaxBodyBytes))
+	latency := time.Since(start)
+	if err != nil {
+		report(false, checkFailureText(ctx, check, fmt.Sprintf("Failed to read response: %v", err)), nil, isIPv6)
+		return
+	}
+
+	dataMap := map[string]interface{}{
+		"URL":        target.URL,
+		"StatusCode": resp.StatusCode,
+		"LatencyMs":  latency.Milliseconds(),
+	}
+	errText := evaluateHttpAssertions(assertions, resp.StatusCode, resp.Header, respBody, dataMap)
+	success := errText == ""
+
+	report(success, errText, dataMap, isIPv6)
+	log.Log(log.Debug, "HTTP check completed for %s %s isIPv6=%v success=%v", member.Details.Name, target.URL, isIPv6, success)
+}
+
+// evaluateHttpAssertions records a pass flag per configured assertion in
+// dataMap and returns the first failure, or an empty string if all pass.
+func evaluateHttpAssertions(a httpAssertions, statusCode int, header http.Header, body []byte, dataMap map[string]interface{}) string {
+	var failures []string
+
+	statusOK := statusCode >= a.StatusMin && statusCode <= a.StatusMax
+	dataMap["StatusOK"] = statusOK
+	if !statusOK {
+		failures = append(failures, fmt.Sprintf("status %d outside %d-%d", statusCode, a.StatusMin, a.StatusMax))
+	}
+
+	if a.Header != "" {
+		values, present := header[http.CanonicalHeaderKey(a.Header)]
+		headerOK := present
+		if present && a.HeaderValue != "" {
+			headerOK = false
+			for _, v := range values {
+				if strings.EqualFold(strings.TrimSpace(v), a.HeaderValue) {
+					headerOK = true
+					break
+				}
+			}
+		}
+		dataMap["HeaderOK"] = headerOK
+		if !headerOK {
+			failures = append(failures, fmt.Sprintf("header %s mismatch", a.Header))
+		}
+	}
+
+	if a.BodyRegex != nil {
+		bodyOK := a.BodyRegex.Match(body)
+		dataMap["BodyRegexOK"] = bodyOK
+		if !bodyOK {
+			failures = append(failures, fmt.Sprintf("body does not match %q", a.BodyRegex.String()))
+		}
+	}
+
+	if a.JSONPath != "" {
+		res := gjson.GetBytes(body, a.JSONPath)
+		jsonOK := res.Exists()
+		if jsonOK && a.HasJSONValue {
+			jsonOK = res.String() == a.JSONValue
+		}
+		dataMap["JsonPathOK"] = jsonOK
+		dataMap["JsonPathValue"] = res.String()
+		if !jsonOK {
+			failures = append(failures, fmt.Sprintf("json %s=%q", a.JSONPath, res.String()))
+		}
+	}
+
+	if len(failures) == 0 {
+		return ""
+	}
+	return "HTTP assertion failed: " + strings.Join(failures, "; ")
+}
@@ -0,0 +1,57 @@
+package monitor
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestEvaluateHttpAssertionsPassesAllConfiguredChecks(t *testing.T) {
+	a, err := parseHttpAssertions(map[string]interface{}{
+		"ExpectHeader":      "Content-Type",
+		"ExpectHeaderValue": "application/json",
+		"BodyRegex":         `"isSyncing":\s*false`,
+		"JsonPath":          "peers",
+		"JsonValue":         float64(12),
+	})
+	if err != nil {
+		t.Fatalf("parseHttpAssertions returned error: %v", err)
+	}
+
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	data := map[string]interface{}{}
+	if got := evaluateHttpAssertions(a, 200, header, []byte(`{"peers":12,"isSyncing": false}`), data); got != "" {
+		t.Fatalf("expected all assertions to pass, got %q", got)
+	}
+	for _, flag := range []string{"StatusOK", "HeaderOK", "BodyRegexOK", "JsonPathOK"} {
+		if data[flag] != true {
+			t.Fatalf("expected %s=true in data, got %#v", flag, data)
+		}
+	}
+}
+
+func TestEvaluateHttpAssertionsReportsFailures(t *testing.T) {
+	a, err := parseHttpAssertions(map[string]interface{}{
+		"ExpectStatusMin": 200,
+		"ExpectStatusMax": 204,
+		"JsonPath":        "status",
+		"JsonValue":       "ok",
+	})
+	if err != nil {
+		t.Fatalf("parseHttpAssertions returned error: %v", err)
+	}
+
+	data := map[string]interface{}{}
+	got := evaluateHttpAssertions(a, 503, http.Header{}, []byte(`{"status":"degraded"}`), data)
+	if got == "" {
+		t.Fatalf("expected assertion failure")
+	}
+	if data["StatusOK"] != false || data["JsonPathOK"] != false || data["JsonPathValue"] != "degraded" {
+		t.Fatalf("unexpected data flags %#v", data)
+	}
+}
+
+func TestParseHttpAssertionsRejectsInvalidRegex(t *testing.T) {
+	if _, err := parseHttpAssertions(map[string]interface{}{"BodyRegex": "("}); err == nil {
+		t.Fatalf("expected invalid BodyRegex to fail")
+	}
+}
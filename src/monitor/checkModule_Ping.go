@@ -1,6 +1,7 @@
 package monitor
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
@@ -15,22 +16,22 @@ func init() {
 	RegisterSiteCheck("ping", PingCheck)
 }
 
-func PingCheck(check cfg.Check, member cfg.Member) {
+func PingCheck(ctx context.Context, check cfg.Check, member cfg.Member) {
 	if member.Service.ServiceIPv4 == "" && member.Service.ServiceIPv6 == "" {
 		UpdateSiteResultLocal(check, member, false, "No IPv4 or IPv6 configured", nil, false)
 		return
 	}
 
 	if member.Service.ServiceIPv4 != "" {
-		runPingSingle(check, member, false)
+		runPingSingle(ctx, check, member, false)
 	}
 
 	if member.Service.ServiceIPv6 != "" {
-		runPingSingle(check, member, true)
+		runPingSingle(ctx, check, member, true)
 	}
 }
 
-func runPingSingle(check cfg.Check, member cfg.Member, isIPv6 bool) {
+func runPingSingle(ctx context.Context, check cfg.Check, member cfg.Member, isIPv6 bool) {
 	var ipToPing string
 	if isIPv6 {
 		ipToPing = member.Service.ServiceIPv6
@@ -47,17 +48,17 @@ func runPingSingle(check cfg.Check, member cfg.Member, isIPv6 bool) {
 	maxLatency := int64(getIntOption(check.ExtraOptions, "MaxLatency", 800))
 
 	options := pingOptions{
-		Count:       pingCount,
-		Interval:    pingInterval,
-		Timeout:     pingTimeout,
-		Size:        pingSize,
-		TTL:         pingTTL,
-		MaxLoss:     maxPacketLoss,
-		MaxLatency:  maxLatency,
+		Count:      pingCount,
+		Interval:   pingInterval,
+		Timeout:    pingTimeout,
+		Size:       pingSize,
+		TTL:        pingTTL,
+		MaxLoss:    maxPacketLoss,
+		MaxLatency: maxLatency,
 	}
-	stats, err := runPing(ipToPing, isIPv6, options)
+	stats, err := runPing(ctx, ipToPing, isIPv6, options)
 	if err != nil {
-		UpdateSiteResultLocal(check, member, false, err.Error(), nil, isIPv6)
+		UpdateSiteResultLocal(check, member, false, checkFailureText(ctx, check, err.Error()), nil, isIPv6)
 		return
 	}
 
@@ -88,16 +89,16 @@ type pingOptions struct {
 	MaxLatency int64
 }
 
-func runPing(ipToPing string, isIPv6 bool, options pingOptions) (*ping.Statistics, error) {
-	stats, err := executePing(ipToPing, isIPv6, options, true)
-	if err != nil && isPrivilegedPingError(err) {
+func runPing(ctx context.Context, ipToPing string, isIPv6 bool, options pingOptions) (*ping.Statistics, error) {
+	stats, err := executePing(ctx, ipToPing, isIPv6, options, true)
+	if err != nil && ctx.Err() == nil && isPrivilegedPingError(err) {
 		log.Log(log.Warn, "Privileged ping failed for %s, retrying unprivileged: %v", ipToPing, err)
-		return executePing(ipToPing, isIPv6, options, false)
+		return executePing(ctx, ipToPing, isIPv6, options, false)
 	}
 	return stats, err
 }
 
-func executePing(ipToPing string, isIPv6 bool, options pingOptions, privileged bool) (*ping.Statistics, error) {
+func executePing(ctx context.Context, ipToPing string, isIPv6 bool, options pingOptions, privileged bool) (*ping.Statistics, error) {
 	pinger, err := ping.NewPinger(ipToPing)
 	if err != nil {
 		return nil, fmt.Errorf("ping error init: %w", err)
@@ -113,9 +114,17 @@ func executePing(ipToPing string, isIPv6 bool, options pingOptions, privileged b
 	pinger.TTL = options.TTL
 	pinger.SetPrivileged(privileged)
 
+	// Stop the pinger early if the check's budget runs out; Run returns normally
+	// after Stop, so the context error is surfaced explicitly below.
+	stopPinger := context.AfterFunc(ctx, pinger.Stop)
+	defer stopPinger()
+
 	if err := pinger.Run(); err != nil {
 		return nil, err
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("ping aborted: %w", err)
+	}
 	return pinger.Statistics(), nil
 }
 
@@ -0,0 +1,76 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	cfg "github.com/ibp-network/ibp-geodns-libs/config"
+	log "github.com/ibp-network/ibp-geodns-libs/logging"
+)
+
+func init() {
+	// RPC check is only valid for RPC service type
+	RegisterEndpointCheckWithTypes("rpc", RpcCheck, []string{"RPC"})
+}
+
+func RpcCheck(ctx context.Context, check cfg.Check, endpoint string, service cfg.Service, member cfg.Member) {
+	target, err := parseCheckTarget(endpoint, "https")
+	if err != nil {
+		UpdateEndpointResultLocal(check, member, service, endpoint, false, fmt.Sprintf("Invalid HTTP RPC target: %v", err), nil, false)
+		return
+	}
+	target.Scheme = httpSchemeForTarget(target.Scheme)
+	target.URL = buildTargetURL(target.Scheme, target.Hostname, target.Port, target.RequestURI)
+
+	ip4 := member.Service.ServiceIPv4
+	ip6 := member.Service.ServiceIPv6
+
+	if ip4 == "" && ip6 == "" {
+		UpdateEndpointResultLocal(check, member, service, endpoint, false, "No IPv4 or IPv6 configured", nil, false)
+		return
+	}
+
+	if ip4 != "" {
+		runRpcSingle(ctx, check, endpoint, target, service, member, ip4, false)
+	}
+	if ip6 != "" {
+		runRpcSingle(ctx, check, endpoint, target, service, member, ip6, true)
+	}
+}
+
+func runRpcSingle(ctx context.Context, check cfg.Check, endpoint string, target CheckTarget, service cfg.Service, member cfg.Member, ip string, isIPv6 bool) {
+	log.Log(log.Debug, "RPC check: endpoint=%s => url=%s (connecting via %s) for %s",
+		endpoint, target.URL, ip, member.Details.Name)
+
+	timeoutSec := getIntOption(check.ExtraOptions, "ConnectTimeout", 10)
+	client := newPinnedHTTPClient(target, ip, timeoutSec, "RPC")
+	defer client.CloseIdleConnections()
+
+	runSubstrateChecks(ctx, check, endpoint, service, member, isIPv6, "RPC", httpSubstrateCall(ctx, client, target.URL))
+}
+
+// httpSubstrateCall posts each JSON-RPC call to url; unlike the WSS transport
+// every call is an independent request/response pair.
+func httpSubstrateCall(ctx context.Context, client *http.Client, url string) substrateCall {
+	return func(method string, params []interface{}, desc string, target interface{}) error {
+		if params == nil {
+			params = []interface{}{}
+		}
+		result, err := ethCall(ctx, client, url, method, params)
+		if err != nil {
+			return fmt.Errorf("%s: %w", desc, err)
+		}
+		if len(result) == 0 {
+			return fmt.Errorf("%s: missing result", desc)
+		}
+		if target == nil {
+			return nil
+		}
+		if err := json.Unmarshal(result, target); err != nil {
+			return fmt.Errorf("%s: invalid result: %w", desc, err)
+		}
+		return nil
+	}
+}
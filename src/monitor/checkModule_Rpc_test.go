@@ -0,0 +1,73 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newSubstrateRPCServer(t *testing.T, results map[string]interface{}) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req JSONRPCRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, ok := results[req.Method]
+		if !ok {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      req.ID,
+				"error":   map[string]interface{}{"code": -32601, "message": "Method not found"},
+			})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+	}))
+}
+
+func TestHTTPSubstrateCallRunsNetworkAndPeerChecks(t *testing.T) {
+	srv := newSubstrateRPCServer(t, map[string]interface{}{
+		"system_chain":       "Polkadot",
+		"chain_getBlockHash": "0x91b1",
+		"chain_getHeader":    map[string]interface{}{"stateRoot": "0xabc"},
+		"system_health":      map[string]interface{}{"peers": 12, "isSyncing": false},
+	})
+	defer srv.Close()
+
+	call := httpSubstrateCall(context.Background(), srv.Client(), srv.URL)
+
+	archive, err := checkFullArchive(call)
+	if err != nil || !archive {
+		t.Fatalf("expected archive check to pass, got %v err=%v", archive, err)
+	}
+	network, err := checkNetwork(call, "polkadot", "0xABC")
+	if err != nil || !network {
+		t.Fatalf("expected network check to pass, got %v err=%v", network, err)
+	}
+	enough, syncing, peers, err := checkPeers(call, 5)
+	if err != nil {
+		t.Fatalf("unexpected peer check error: %v", err)
+	}
+	if !enough || syncing || peers != 12 {
+		t.Fatalf("unexpected peer check result enough=%v syncing=%v peers=%d", enough, syncing, peers)
+	}
+}
+
+func TestHTTPSubstrateCallSurfacesRPCErrors(t *testing.T) {
+	srv := newSubstrateRPCServer(t, map[string]interface{}{})
+	defer srv.Close()
+
+	call := httpSubstrateCall(context.Background(), srv.Client(), srv.URL)
+	var hash string
+	err := call("chain_getBlockHash", nil, "chain_getBlockHash()", &hash)
+	if err == nil {
+		t.Fatalf("expected rpc error to be returned")
+	}
+	if got := err.Error(); got != "chain_getBlockHash(): RPC error -32601: Method not found" {
+		t.Fatalf("unexpected error text %q", got)
+	}
+}
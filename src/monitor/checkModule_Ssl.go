@@ -1,9 +1,17 @@
 package monitor
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"net"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	cfg "github.com/ibp-network/ibp-geodns-libs/config"
@@ -15,7 +23,80 @@ func init() {
 	RegisterDomainCheckWithTypes("ssl", SslCheck, []string{"RPC", "ETHRPC"})
 }
 
-func SslCheck(check cfg.Check, domain string, service cfg.Service, member cfg.Member) {
+var tlsVersionNames = map[uint16]string{
+	tls.VersionTLS10: "1.0",
+	tls.VersionTLS11: "1.1",
+	tls.VersionTLS12: "1.2",
+	tls.VersionTLS13: "1.3",
+}
+
+// legacyTLSResult is the outcome of the TLS 1.0/1.1 probe.
+type legacyTLSResult int
+
+const (
+	legacyTLSSkipped legacyTLSResult = iota
+	legacyTLSRejected
+	legacyTLSAccepted
+	legacyTLSInconclusive
+)
+
+var legacyTLSResultNames = map[legacyTLSResult]string{
+	legacyTLSSkipped:      "skipped",
+	legacyTLSRejected:     "rejected",
+	legacyTLSAccepted:     "accepted",
+	legacyTLSInconclusive: "inconclusive",
+}
+
+// tlsPolicy is the set of requirements read from the ssl check's ExtraOptions.
+type tlsPolicy struct {
+	MinDaysToExpiry   int
+	MinVersion        uint16
+	RejectLegacyTLS   bool
+	RequireOCSPStaple bool
+	AllowedIssuers    []string
+	AllowedKeyTypes   []string
+	MinRSABits        int
+	SanScope          string // "member" or "domain"
+	Roots             *x509.CertPool
+}
+
+func parseTLSPolicy(extraOptions map[string]interface{}) (tlsPolicy, error) {
+	p := tlsPolicy{
+		MinDaysToExpiry:   getIntOption(extraOptions, "MinDaysToExpiry", 5),
+		RejectLegacyTLS:   getIntOption(extraOptions, "RejectLegacyTLS", 0) == 1,
+		RequireOCSPStaple: getIntOption(extraOptions, "RequireOCSPStaple", 0) == 1,
+		AllowedIssuers:    getStringListOption(extraOptions, "AllowedIssuers"),
+		AllowedKeyTypes:   getStringListOption(extraOptions, "AllowedKeyTypes"),
+		MinRSABits:        getIntOption(extraOptions, "MinRSABits", 2048),
+		SanScope:          strings.ToLower(getStringOption(extraOptions, "SanScope", "domain")),
+	}
+
+	// MinTLSVersion may be written as a JSON number such as 1.3.
+	minVersion := "1.2"
+	switch v := extraOptions["MinTLSVersion"].(type) {
+	case nil:
+	case string:
+		minVersion = v
+	case float64:
+		minVersion = strconv.FormatFloat(v, 'f', 1, 64)
+	default:
+		return tlsPolicy{}, fmt.Errorf("invalid MinTLSVersion %v", v)
+	}
+	for v, name := range tlsVersionNames {
+		if name == minVersion {
+			p.MinVersion = v
+		}
+	}
+	if p.MinVersion == 0 {
+		return tlsPolicy{}, fmt.Errorf("invalid MinTLSVersion %q", minVersion)
+	}
+	if p.SanScope != "member" && p.SanScope != "domain" {
+		return tlsPolicy{}, fmt.Errorf("invalid SanScope %q", p.SanScope)
+	}
+	return p, nil
+}
+
+func SslCheck(ctx context.Context, check cfg.Check, domain string, service cfg.Service, member cfg.Member) {
 	target, err := parseCheckTarget(domain, "https")
 	if err != nil {
 		UpdateDomainResultLocal(check, domain, service, member, false,
@@ -23,6 +104,12 @@ func SslCheck(check cfg.Check, domain string, service cfg.Service, member cfg.Me
 		return
 	}
 
+	policy, err := parseTLSPolicy(check.ExtraOptions)
+	if err != nil {
+		UpdateDomainResultLocal(check, target.Hostname, service, member, false, err.Error(), nil, false)
+		return
+	}
+
 	ip4 := member.Service.ServiceIPv4
 	ip6 := member.Service.ServiceIPv6
 	if ip4 == "" && ip6 == "" {
@@ -30,71 +117,270 @@ func SslCheck(check cfg.Check, domain string, service cfg.Service, member cfg.Me
 			"No IPv4 or IPv6 configured", nil, false)
 		return
 	}
+
+	sanDomains := []string{target.Hostname}
+	if policy.SanScope == "member" {
+		sanDomains = memberServedDomains(cfg.GetConfig(), check.Name, member)
+		if len(sanDomains) == 0 {
+			sanDomains = []string{target.Hostname}
+		}
+	}
+
 	if ip4 != "" {
-		dialAndCheckTLS(check, target, service, member, ip4, false)
+		dialAndCheckTLS(ctx, check, target, service, member, ip4, false, policy, sanDomains)
 	}
 	if ip6 != "" {
-		dialAndCheckTLS(check, target, service, member, ip6, true)
+		dialAndCheckTLS(ctx, check, target, service, member, ip6, true, policy, sanDomains)
 	}
 }
 
 func dialAndCheckTLS(
+	ctx context.Context,
 	check cfg.Check,
 	target CheckTarget,
 	service cfg.Service,
 	member cfg.Member,
 	ip string,
 	isIPv6 bool,
+	policy tlsPolicy,
+	sanDomains []string,
 ) {
 	timeoutSec := getIntOption(check.ExtraOptions, "ConnectTimeout", 5)
 	timeout := time.Duration(timeoutSec) * time.Second
-	conn, err := net.DialTimeout("tcp", target.DialAddress(ip), timeout)
+
+	// Verification is done afterwards so an invalid chain is reported as a
+	// finding alongside the rest of the policy instead of a bare handshake error.
+	state, err := tlsHandshake(ctx, target, ip, timeout, &tls.Config{
+		ServerName:         target.Hostname,
+		InsecureSkipVerify: true,
+	})
 	if err != nil {
 		UpdateDomainResultLocal(check, target.Hostname, service, member, false,
-			fmt.Sprintf("TCP connect error: %v", err), nil, isIPv6)
+			checkFailureText(ctx, check, err.Error()), nil, isIPv6)
+		return
+	}
+	if len(state.PeerCertificates) == 0 {
+		UpdateDomainResultLocal(check, target.Hostname, service, member, false, "No certificate found", nil, isIPv6)
 		return
 	}
-	defer conn.Close()
-	_ = conn.SetDeadline(time.Now().Add(timeout))
 
-	tlsConn := tls.Client(conn, &tls.Config{
+	legacy, legacyErr := legacyTLSSkipped, error(nil)
+	if policy.RejectLegacyTLS {
+		legacy, legacyErr = probeLegacyTLS(ctx, target, ip, timeout)
+	}
+
+	dataMap, failures := evaluateTLSPolicy(policy, state, target.Hostname, sanDomains, legacy, time.Now())
+	dataMap["Port"] = target.Port
+
+	success := len(failures) == 0
+	errText := strings.Join(failures, "; ")
+
+	if success && legacy == legacyTLSInconclusive {
+		UpdateDomainResultInconclusive(check, target.Hostname, service, member,
+			fmt.Sprintf("Legacy TLS probe inconclusive: %v", legacyErr), dataMap, isIPv6)
+		return
+	}
+
+	UpdateDomainResultLocal(check, target.Hostname, service, member, success, errText, dataMap, isIPv6)
+	log.Log(log.Debug, "SSL check completed for %s %s isIPv6=%v success=%v", member.Details.Name, target.URL, isIPv6, success)
+}
+
+// probeLegacyTLS offers only TLS 1.0 and 1.1, with every cipher suite Go
+// implements including the insecure ones, so a server that still speaks a
+// legacy version can find a suite to complete the handshake with.
+func probeLegacyTLS(ctx context.Context, target CheckTarget, ip string, timeout time.Duration) (legacyTLSResult, error) {
+	var suites []uint16
+	for _, cs := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		suites = append(suites, cs.ID)
+	}
+	_, err := tlsHandshake(ctx, target, ip, timeout, &tls.Config{
 		ServerName:         target.Hostname,
-		InsecureSkipVerify: false,
+		InsecureSkipVerify: true,
+		MinVersion:         tls.VersionTLS10,
+		MaxVersion:         tls.VersionTLS11,
+		CipherSuites:       suites,
 	})
-	err = tlsConn.Handshake()
+	return classifyLegacyTLS(err), err
+}
+
+// classifyLegacyTLS reads the legacy probe's handshake error. Only a
+// protocol_version alert, or a ServerHello for a newer version, shows the
+// server refuses TLS 1.0/1.1. Any other failure, such as a handshake_failure
+// over cipher suites or a dropped connection, says nothing about the version.
+func classifyLegacyTLS(err error) legacyTLSResult {
+	switch {
+	case err == nil:
+		return legacyTLSAccepted
+	case strings.Contains(err.Error(), "protocol version not supported"),
+		strings.Contains(err.Error(), "server selected unsupported protocol version"):
+		return legacyTLSRejected
+	default:
+		return legacyTLSInconclusive
+	}
+}
+
+func tlsHandshake(ctx context.Context, target CheckTarget, ip string, timeout time.Duration, tlsCfg *tls.Config) (tls.ConnectionState, error) {
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", target.DialAddress(ip))
 	if err != nil {
-		UpdateDomainResultLocal(check, target.Hostname, service, member, false,
-			fmt.Sprintf("TLS handshake failed: %v", err), nil, isIPv6)
-		return
+		return tls.ConnectionState{}, fmt.Errorf("TCP connect error: %v", err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+
+	tlsConn := tls.Client(conn, tlsCfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return tls.ConnectionState{}, fmt.Errorf("TLS handshake failed: %v", err)
 	}
 	defer tlsConn.Close()
+	return tlsConn.ConnectionState(), nil
+}
 
-	certs := tlsConn.ConnectionState().PeerCertificates
-	if len(certs) == 0 {
-		UpdateDomainResultLocal(check, target.Hostname, service, member, false, "No certificate found", nil, isIPv6)
-		return
+// evaluateTLSPolicy checks the handshake and the legacy probe against policy.
+// It returns the findings, each with a pass flag, and the failure reasons. An
+// inconclusive legacy probe is not a failure.
+func evaluateTLSPolicy(policy tlsPolicy, state tls.ConnectionState, hostname string, sanDomains []string, legacy legacyTLSResult, now time.Time) (map[string]interface{}, []string) {
+	cert := state.PeerCertificates[0]
+	var failures []string
+
+	daysUntilExpiry := int(cert.NotAfter.Sub(now).Hours() / 24)
+	expiryOK := daysUntilExpiry >= policy.MinDaysToExpiry
+	if !expiryOK {
+		failures = append(failures, fmt.Sprintf("Less than %d days to expiry", policy.MinDaysToExpiry))
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range state.PeerCertificates[1:] {
+		intermediates.AddCert(c)
+	}
+	_, chainErr := cert.Verify(x509.VerifyOptions{
+		DNSName:       hostname,
+		Roots:         policy.Roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+	})
+	chainOK := chainErr == nil
+	if !chainOK {
+		failures = append(failures, fmt.Sprintf("Certificate chain invalid: %v", chainErr))
 	}
 
-	cert := certs[0]
-	daysUntilExpiry := int(time.Until(cert.NotAfter).Hours() / 24)
-	success := true
-	errText := ""
-	if daysUntilExpiry < 5 {
-		success = false
-		errText = "Less than 5 days to expiry"
+	var missingSANs []string
+	for _, d := range sanDomains {
+		if cert.VerifyHostname(d) != nil {
+			missingSANs = append(missingSANs, d)
+		}
+	}
+	sanOK := len(missingSANs) == 0
+	if !sanOK {
+		failures = append(failures, "Certificate does not cover "+strings.Join(missingSANs, ", "))
+	}
+
+	versionOK := state.Version >= policy.MinVersion
+	if !versionOK {
+		failures = append(failures, fmt.Sprintf("Negotiated TLS %s below minimum %s",
+			tlsVersionName(state.Version), tlsVersionName(policy.MinVersion)))
+	}
+
+	legacyOK := !policy.RejectLegacyTLS || legacy != legacyTLSAccepted
+	if !legacyOK {
+		failures = append(failures, "Server accepts TLS 1.0/1.1")
+	}
+
+	ocspStapled := len(state.OCSPResponse) > 0
+	if policy.RequireOCSPStaple && !ocspStapled {
+		failures = append(failures, "No OCSP response stapled")
+	}
+
+	issuer := cert.Issuer.String()
+	issuerOK := len(policy.AllowedIssuers) == 0
+	for _, allowed := range policy.AllowedIssuers {
+		if strings.Contains(strings.ToLower(issuer), strings.ToLower(allowed)) {
+			issuerOK = true
+			break
+		}
+	}
+	if !issuerOK {
+		failures = append(failures, "Issuer not allowed: "+issuer)
+	}
+
+	keyType, keyOK := certificateKeyType(cert, policy.MinRSABits)
+	if keyOK && len(policy.AllowedKeyTypes) > 0 {
+		keyOK = false
+		for _, allowed := range policy.AllowedKeyTypes {
+			if strings.HasPrefix(strings.ToUpper(keyType), strings.ToUpper(allowed)) {
+				keyOK = true
+				break
+			}
+		}
+	}
+	if !keyOK {
+		failures = append(failures, "Key type not allowed: "+keyType)
 	}
 
 	dataMap := map[string]interface{}{
-		"ExpiryTimestamp": cert.NotAfter.Unix(),
-		"DaysUntilExpiry": daysUntilExpiry,
-		"Port":            target.Port,
+		"ExpiryTimestamp":   cert.NotAfter.Unix(),
+		"DaysUntilExpiry":   daysUntilExpiry,
+		"MinDaysToExpiry":   policy.MinDaysToExpiry,
+		"ExpiryOK":          expiryOK,
+		"ChainValid":        chainOK,
+		"SANCoverageOK":     sanOK,
+		"MissingSANs":       missingSANs,
+		"TLSVersion":        tlsVersionName(state.Version),
+		"MinTLSVersionOK":   versionOK,
+		"LegacyTLSRejected": legacyOK && legacy != legacyTLSInconclusive,
+		"LegacyTLSProbe":    legacyTLSResultNames[legacy],
+		"OCSPStapled":       ocspStapled,
+		"Issuer":            issuer,
+		"IssuerOK":          issuerOK,
+		"KeyType":           keyType,
+		"KeyTypeOK":         keyOK,
+	}
+	return dataMap, failures
+}
+
+// certificateKeyType names the certificate's public key, e.g. "ECDSA-P256" or
+// "RSA-2048", and reports whether RSA keys meet minRSABits.
+func certificateKeyType(cert *x509.Certificate, minRSABits int) (string, bool) {
+	switch k := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		bits := k.N.BitLen()
+		return fmt.Sprintf("RSA-%d", bits), bits >= minRSABits
+	case *ecdsa.PublicKey:
+		return "ECDSA-" + strings.ReplaceAll(k.Curve.Params().Name, "-", ""), true
+	case ed25519.PublicKey:
+		return "Ed25519", true
+	default:
+		return cert.PublicKeyAlgorithm.String(), true
+	}
+}
+
+func tlsVersionName(v uint16) string {
+	if name, ok := tlsVersionNames[v]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", v)
+}
+
+// memberServedDomains lists every domain of the services member is assigned to
+// that the check applies to, sorted.
+func memberServedDomains(c cfg.Config, checkName string, member cfg.Member) []string {
+	set := make(map[string]struct{})
+	for svcName, svc := range c.Services {
+		if !isCheckValidForServiceType(checkName, "domain", svc.Configuration.ServiceType) {
+			continue
+		}
+		if member.Membership.Level < svc.Configuration.LevelRequired || !assignedToService(svcName, member) {
+			continue
+		}
+		for d := range extractDomains(svc) {
+			set[d] = struct{}{}
+		}
 	}
 
-	if success {
-		UpdateDomainResultLocal(check, target.Hostname, service, member, true, "", dataMap, isIPv6)
-		log.Log(log.Debug, "SSL check completed for %s %s isIPv6=%v success=%v", member.Details.Name, target.URL, isIPv6, true)
-	} else {
-		UpdateDomainResultLocal(check, target.Hostname, service, member, false, errText, dataMap, isIPv6)
-		log.Log(log.Debug, "SSL check failed for %s %s isIPv6=%v success=%v", member.Details.Name, target.URL, isIPv6, false)
+	out := make([]string, 0, len(set))
+	for d := range set {
+		out = append(out, d)
 	}
+	sort.Strings(out)
+	return out
 }
@@ -0,0 +1,240 @@
+package monitor
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// issueTestCert returns a leaf for dnsNames signed by a fresh CA, and a pool holding that CA.
+func issueTestCert(t *testing.T, dnsNames []string, notAfter time.Time) (*x509.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate CA key: %v", err)
+	}
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Root", Organization: []string{"Example CA"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("create CA: %v", err)
+	}
+	ca, _ := x509.ParseCertificate(caDER)
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate leaf key: %v", err)
+	}
+	leafTmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTmpl, ca, &leafKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("create leaf: %v", err)
+	}
+	leaf, _ := x509.ParseCertificate(leafDER)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	return leaf, roots
+}
+
+func TestEvaluateTLSPolicyPassesCompliantCertificate(t *testing.T) {
+	leaf, roots := issueTestCert(t, []string{"rpc.example.net", "eth.example.net"}, time.Now().Add(60*24*time.Hour))
+
+	policy, err := parseTLSPolicy(map[string]interface{}{
+		"MinDaysToExpiry": 30,
+		"AllowedIssuers":  []interface{}{"Example CA"},
+		"AllowedKeyTypes": []interface{}{"ECDSA"},
+	})
+	if err != nil {
+		t.Fatalf("parseTLSPolicy returned error: %v", err)
+	}
+	policy.Roots = roots
+
+	state := tls.ConnectionState{Version: tls.VersionTLS13, PeerCertificates: []*x509.Certificate{leaf}}
+	data, failures := evaluateTLSPolicy(policy, state, "rpc.example.net",
+		[]string{"eth.example.net", "rpc.example.net"}, legacyTLSSkipped, time.Now())
+	if len(failures) != 0 {
+		t.Fatalf("expected no failures, got %v", failures)
+	}
+	for _, flag := range []string{"ExpiryOK", "ChainValid", "SANCoverageOK", "MinTLSVersionOK", "LegacyTLSRejected", "IssuerOK", "KeyTypeOK"} {
+		if data[flag] != true {
+			t.Fatalf("expected %s=true, got %#v", flag, data)
+		}
+	}
+	if data["KeyType"] != "ECDSA-P256" || data["TLSVersion"] != "1.3" || data["OCSPStapled"] != false {
+		t.Fatalf("unexpected findings %#v", data)
+	}
+}
+
+func TestEvaluateTLSPolicyReportsEachFailure(t *testing.T) {
+	leaf, _ := issueTestCert(t, []string{"rpc.example.net"}, time.Now().Add(3*24*time.Hour))
+
+	policy, err := parseTLSPolicy(map[string]interface{}{
+		"MinDaysToExpiry":   14,
+		"RejectLegacyTLS":   1,
+		"RequireOCSPStaple": 1,
+		"AllowedKeyTypes":   []interface{}{"RSA"},
+	})
+	if err != nil {
+		t.Fatalf("parseTLSPolicy returned error: %v", err)
+	}
+	policy.Roots = x509.NewCertPool() // the test CA is not trusted
+
+	state := tls.ConnectionState{Version: tls.VersionTLS11, PeerCertificates: []*x509.Certificate{leaf}}
+	data, failures := evaluateTLSPolicy(policy, state, "rpc.example.net",
+		[]string{"rpc.example.net", "eth.example.net"}, legacyTLSAccepted, time.Now())
+
+	joined := strings.Join(failures, "; ")
+	for _, want := range []string{"Less than 14 days", "chain invalid", "eth.example.net", "below minimum 1.2", "TLS 1.0/1.1", "OCSP", "Key type"} {
+		if !strings.Contains(joined, want) {
+			t.Fatalf("expected failure containing %q, got %q", want, joined)
+		}
+	}
+	if missing, _ := data["MissingSANs"].([]string); len(missing) != 1 || missing[0] != "eth.example.net" {
+		t.Fatalf("unexpected MissingSANs %#v", data["MissingSANs"])
+	}
+}
+
+func TestParseTLSPolicyRejectsUnknownVersion(t *testing.T) {
+	if _, err := parseTLSPolicy(map[string]interface{}{"MinTLSVersion": "1.4"}); err == nil {
+		t.Fatalf("expected invalid MinTLSVersion to fail")
+	}
+}
+
+func TestParseTLSPolicyKeepsLegacyDefaults(t *testing.T) {
+	policy, err := parseTLSPolicy(nil)
+	if err != nil {
+		t.Fatalf("parseTLSPolicy returned error: %v", err)
+	}
+	if policy.RejectLegacyTLS || policy.SanScope != "domain" || policy.MinVersion != tls.VersionTLS12 {
+		t.Fatalf("expected opt-in policy defaults, got %+v", policy)
+	}
+}
+
+func TestParseTLSPolicyAcceptsNumericVersion(t *testing.T) {
+	policy, err := parseTLSPolicy(map[string]interface{}{"MinTLSVersion": 1.3})
+	if err != nil {
+		t.Fatalf("parseTLSPolicy returned error: %v", err)
+	}
+	if policy.MinVersion != tls.VersionTLS13 {
+		t.Fatalf("expected TLS 1.3 minimum, got %s", tlsVersionName(policy.MinVersion))
+	}
+	if _, err := parseTLSPolicy(map[string]interface{}{"MinTLSVersion": true}); err == nil {
+		t.Fatalf("expected non-numeric, non-string MinTLSVersion to fail")
+	}
+}
+
+// serveTLS accepts TLS handshakes under srvCfg on a loopback port until the
+// test ends, and returns a target for it.
+func serveTLS(t *testing.T, srvCfg *tls.Config) CheckTarget {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "rpc.example.net"},
+		DNSNames:     []string{"rpc.example.net"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	srvCfg.Certificates = []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", srvCfg)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+	target, err := parseCheckTarget("https://rpc.example.net:"+port, "https")
+	if err != nil {
+		t.Fatalf("parseCheckTarget: %v", err)
+	}
+	return target
+}
+
+func TestProbeLegacyTLSClassifiesServerReplies(t *testing.T) {
+	testCases := []struct {
+		name   string
+		srvCfg *tls.Config
+		want   legacyTLSResult
+	}{
+		{"modern only", &tls.Config{MinVersion: tls.VersionTLS12}, legacyTLSRejected},
+		{"legacy accepted", &tls.Config{MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS11}, legacyTLSAccepted},
+		{"no shared cipher suite", &tls.Config{
+			MinVersion:   tls.VersionTLS10,
+			CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+		}, legacyTLSInconclusive},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			target := serveTLS(t, tc.srvCfg)
+			got, err := probeLegacyTLS(context.Background(), target, "127.0.0.1", 5*time.Second)
+			if got != tc.want {
+				t.Fatalf("expected %s, got %s (err %v)", legacyTLSResultNames[tc.want], legacyTLSResultNames[got], err)
+			}
+		})
+	}
+}
+
+func TestEvaluateTLSPolicyDoesNotFailInconclusiveLegacyProbe(t *testing.T) {
+	leaf, roots := issueTestCert(t, []string{"rpc.example.net"}, time.Now().Add(60*24*time.Hour))
+
+	policy, err := parseTLSPolicy(map[string]interface{}{"RejectLegacyTLS": 1})
+	if err != nil {
+		t.Fatalf("parseTLSPolicy returned error: %v", err)
+	}
+	policy.Roots = roots
+
+	state := tls.ConnectionState{Version: tls.VersionTLS13, PeerCertificates: []*x509.Certificate{leaf}}
+	data, failures := evaluateTLSPolicy(policy, state, "rpc.example.net",
+		[]string{"rpc.example.net"}, legacyTLSInconclusive, time.Now())
+	if len(failures) != 0 {
+		t.Fatalf("expected no failures, got %v", failures)
+	}
+	if data["LegacyTLSRejected"] != false || data["LegacyTLSProbe"] != "inconclusive" {
+		t.Fatalf("expected the probe to be recorded as inconclusive, got %#v", data)
+	}
+}
@@ -0,0 +1,324 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	cfg "github.com/ibp-network/ibp-geodns-libs/config"
+	log "github.com/ibp-network/ibp-geodns-libs/logging"
+
+	"github.com/gorilla/websocket"
+)
+
+func init() {
+	// Subscription check exercises the WebSocket pub/sub path dApps depend on
+	RegisterEndpointCheckWithTypes("subscription", SubscriptionCheck, []string{"RPC"})
+}
+
+// headSubscription describes one Substrate head subscription.
+type headSubscription struct {
+	Name         string
+	Subscribe    string
+	Notification string
+	Unsubscribe  string
+}
+
+var headSubscriptions = map[string]headSubscription{
+	"newHeads": {
+		Name:         "NewHeads",
+		Subscribe:    "chain_subscribeNewHeads",
+		Notification: "chain_newHead",
+		Unsubscribe:  "chain_unsubscribeNewHeads",
+	},
+	"finalizedHeads": {
+		Name:         "FinalizedHeads",
+		Subscribe:    "chain_subscribeFinalizedHeads",
+		Notification: "chain_finalizedHead",
+		Unsubscribe:  "chain_unsubscribeFinalizedHeads",
+	},
+}
+
+type subscriptionOptions struct {
+	Notifications int
+	Deadline      time.Time     // end of the Budget shared by every subscription and IP family
+	MaxStall      time.Duration // longest wait for any single notification
+	ReadTimeout   time.Duration // for the subscribe and unsubscribe responses
+}
+
+// subscriptionStats is what one subscription delivered before it was closed.
+type subscriptionStats struct {
+	Notifications int
+	FirstBlock    int64
+	LastBlock     int64
+	Intervals     []time.Duration
+	Unsubscribed  bool
+}
+
+type subscriptionMessage struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+	Params struct {
+		Subscription json.RawMessage `json:"subscription"`
+		Result       json.RawMessage `json:"result"`
+	} `json:"params"`
+}
+
+func SubscriptionCheck(ctx context.Context, check cfg.Check, endpoint string, service cfg.Service, member cfg.Member) {
+	target, err := parseCheckTarget(endpoint, "wss")
+	if err != nil {
+		UpdateEndpointResultLocal(check, member, service, endpoint, false, fmt.Sprintf("Invalid WebSocket target: %v", err), nil, false)
+		return
+	}
+	target.Scheme = websocketSchemeForTarget(target.Scheme)
+	target.URL = buildTargetURL(target.Scheme, target.Hostname, target.Port, target.RequestURI)
+
+	subs, err := subscriptionsFromOptions(check.ExtraOptions)
+	if err != nil {
+		UpdateEndpointResultLocal(check, member, service, endpoint, false, err.Error(), nil, false)
+		return
+	}
+
+	ip4 := member.Service.ServiceIPv4
+	ip6 := member.Service.ServiceIPv6
+	if ip4 == "" && ip6 == "" {
+		UpdateEndpointResultLocal(check, member, service, endpoint, false, "No IPv4 or IPv6 configured", nil, false)
+		return
+	}
+
+	opts := subscriptionOptionsFor(ctx, check, time.Now())
+	if ip4 != "" {
+		runSubscriptionSingle(ctx, check, endpoint, target, service, member, ip4, false, subs, opts)
+	}
+	if ip6 != "" {
+		runSubscriptionSingle(ctx, check, endpoint, target, service, member, ip6, true, subs, opts)
+	}
+}
+
+// subscriptionOptionsFor reads the check's options. Budget seconds, default
+// 120, cover every subscription on both IP families, and end no later than
+// the check's own deadline.
+func subscriptionOptionsFor(ctx context.Context, check cfg.Check, now time.Time) subscriptionOptions {
+	deadline := now.Add(time.Duration(getIntOption(check.ExtraOptions, "Budget", 120)) * time.Second)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	return subscriptionOptions{
+		Notifications: max(getIntOption(check.ExtraOptions, "Notifications", 3), 1),
+		Deadline:      deadline,
+		MaxStall:      time.Duration(getIntOption(check.ExtraOptions, "MaxStall", 30)) * time.Second,
+		ReadTimeout:   time.Duration(getIntOption(check.ExtraOptions, "ReadTimeout", 15)) * time.Second,
+	}
+}
+
+func subscriptionsFromOptions(extraOptions map[string]interface{}) ([]headSubscription, error) {
+	names := getStringListOption(extraOptions, "Subscriptions")
+	if len(names) == 0 {
+		names = []string{"newHeads", "finalizedHeads"}
+	}
+	out := make([]headSubscription, 0, len(names))
+	for _, name := range names {
+		sub, ok := headSubscriptions[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown subscription %q", name)
+		}
+		out = append(out, sub)
+	}
+	return out, nil
+}
+
+func runSubscriptionSingle(ctx context.Context, check cfg.Check, endpoint string, target CheckTarget, service cfg.Service, member cfg.Member, ip string, isIPv6 bool, subs []headSubscription, opts subscriptionOptions) {
+	c, err := dialWebsocket(ctx, check, target, ip)
+	if err != nil {
+		UpdateEndpointResultLocal(check, member, service, endpoint, false,
+			checkFailureText(ctx, check, fmt.Sprintf("Failed to connect on IP=%s => %v", ip, err)), nil, isIPv6)
+		return
+	}
+	defer c.Close()
+
+	stopClose := context.AfterFunc(ctx, func() { _ = c.Close() })
+	defer stopClose()
+
+	nextID := 0
+	newID := func() int {
+		nextID++
+		return nextID
+	}
+
+	dataMap := make(map[string]interface{})
+	var failures []string
+	for _, sub := range subs {
+		stats, err := runHeadSubscription(c, sub, opts, newID)
+		dataMap[sub.Name] = subscriptionData(stats, err)
+		if err != nil {
+			// The connection may be left mid-subscription or unreadable, so later subscriptions are skipped.
+			failures = append(failures, fmt.Sprintf("%s: %v", sub.Name, err))
+			break
+		}
+	}
+
+	success := len(failures) == 0
+	errText := ""
+	if !success {
+		errText = checkFailureText(ctx, check, strings.Join(failures, "; "))
+	}
+
+	UpdateEndpointResultLocal(check, member, service, endpoint, success, errText, dataMap, isIPv6)
+	log.Log(log.Debug, "Subscription check completed for %s %s isIPv6=%v success=%v", member.Details.Name, endpoint, isIPv6, success)
+}
+
+func subscriptionData(stats subscriptionStats, err error) map[string]interface{} {
+	out := map[string]interface{}{
+		"Notifications": stats.Notifications,
+		"Unsubscribed":  stats.Unsubscribed,
+	}
+	if stats.Notifications > 0 {
+		out["FirstBlock"] = stats.FirstBlock
+		out["LastBlock"] = stats.LastBlock
+	}
+	if len(stats.Intervals) > 0 {
+		var total, longest time.Duration
+		for _, d := range stats.Intervals {
+			total += d
+			longest = max(longest, d)
+		}
+		out["AvgIntervalMs"] = (total / time.Duration(len(stats.Intervals))).Milliseconds()
+		out["MaxIntervalMs"] = longest.Milliseconds()
+	}
+	if err != nil {
+		out["Error"] = err.Error()
+	}
+	return out
+}
+
+// runHeadSubscription subscribes to sub on c, waits for opts.Notifications
+// headers with non-decreasing block numbers that advance overall, then
+// unsubscribes. The stats are filled in as far as the subscription got.
+func runHeadSubscription(c *websocket.Conn, sub headSubscription, opts subscriptionOptions, newID func() int) (subscriptionStats, error) {
+	var stats subscriptionStats
+
+	subID := newID()
+	if !sendJSONRPCRequest(c, JSONRPCRequest{JSONRPC: "2.0", Method: sub.Subscribe, Params: []interface{}{}, ID: subID}) {
+		return stats, fmt.Errorf("%s: failed to send request", sub.Subscribe)
+	}
+	resp, err := readSubscriptionReply(c, subID, time.Now().Add(opts.ReadTimeout), sub.Subscribe)
+	if err != nil {
+		return stats, err
+	}
+	subscription := strings.Trim(string(resp.Result), `"`)
+	if subscription == "" {
+		return stats, fmt.Errorf("%s: empty subscription id", sub.Subscribe)
+	}
+
+	last := time.Now()
+	for stats.Notifications < opts.Notifications {
+		deadline := last.Add(opts.MaxStall)
+		if opts.Deadline.Before(deadline) {
+			deadline = opts.Deadline
+		}
+		msg, err := readSubscriptionMessage(c, deadline, sub.Notification)
+		if err != nil {
+			if isTimeoutError(err) && !time.Now().Before(opts.Deadline) {
+				return stats, fmt.Errorf("notification budget used up after %d of %d", stats.Notifications, opts.Notifications)
+			}
+			if isTimeoutError(err) {
+				return stats, fmt.Errorf("notifications stalled after %d of %d", stats.Notifications, opts.Notifications)
+			}
+			return stats, err
+		}
+		if msg.Method != sub.Notification || strings.Trim(string(msg.Params.Subscription), `"`) != subscription {
+			continue
+		}
+
+		var header struct {
+			Number string `json:"number"`
+		}
+		if err := json.Unmarshal(msg.Params.Result, &header); err != nil {
+			return stats, fmt.Errorf("%s: invalid header: %v", sub.Notification, err)
+		}
+		number, ok := parseBlockNumber(header.Number)
+		if !ok {
+			return stats, fmt.Errorf("%s: invalid block number %q", sub.Notification, header.Number)
+		}
+
+		now := time.Now()
+		if stats.Notifications == 0 {
+			stats.FirstBlock = number
+		} else {
+			if number < stats.LastBlock {
+				return stats, fmt.Errorf("block number went backwards from %d to %d", stats.LastBlock, number)
+			}
+			stats.Intervals = append(stats.Intervals, now.Sub(last))
+		}
+		stats.LastBlock = number
+		stats.Notifications++
+		last = now
+	}
+	if opts.Notifications > 1 && stats.LastBlock <= stats.FirstBlock {
+		return stats, fmt.Errorf("block number stuck at %d", stats.LastBlock)
+	}
+
+	unsubID := newID()
+	if !sendJSONRPCRequest(c, JSONRPCRequest{JSONRPC: "2.0", Method: sub.Unsubscribe, Params: []interface{}{subscription}, ID: unsubID}) {
+		return stats, fmt.Errorf("%s: failed to send request", sub.Unsubscribe)
+	}
+	resp, err = readSubscriptionReply(c, unsubID, time.Now().Add(opts.ReadTimeout), sub.Unsubscribe)
+	if err != nil {
+		return stats, err
+	}
+	var ok bool
+	if err := json.Unmarshal(resp.Result, &ok); err != nil || !ok {
+		return stats, fmt.Errorf("%s: not confirmed (%s)", sub.Unsubscribe, string(resp.Result))
+	}
+	stats.Unsubscribed = true
+	return stats, nil
+}
+
+// readSubscriptionReply reads until the response to id arrives, skipping any
+// notifications still in flight.
+func readSubscriptionReply(c *websocket.Conn, id int, deadline time.Time, desc string) (subscriptionMessage, error) {
+	for {
+		msg, err := readSubscriptionMessage(c, deadline, desc)
+		if err != nil {
+			return subscriptionMessage{}, err
+		}
+		if msg.ID == nil || *msg.ID != id {
+			continue
+		}
+		if msg.Error != nil {
+			return subscriptionMessage{}, fmt.Errorf("%s: rpc error %d: %s", desc, msg.Error.Code, msg.Error.Message)
+		}
+		if len(msg.Result) == 0 {
+			return subscriptionMessage{}, fmt.Errorf("%s: missing result", desc)
+		}
+		return msg, nil
+	}
+}
+
+func readSubscriptionMessage(c *websocket.Conn, deadline time.Time, desc string) (subscriptionMessage, error) {
+	if err := c.SetReadDeadline(deadline); err != nil {
+		return subscriptionMessage{}, fmt.Errorf("%s: failed to set read deadline: %w", desc, err)
+	}
+	_, raw, err := c.ReadMessage()
+	if err != nil {
+		return subscriptionMessage{}, fmt.Errorf("%s: %w", desc, err)
+	}
+	var msg subscriptionMessage
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return subscriptionMessage{}, fmt.Errorf("%s: failed to decode message: %w", desc, err)
+	}
+	return msg, nil
+}
+
+func isTimeoutError(err error) bool {
+	var timeout interface{ Timeout() bool }
+	return errors.As(err, &timeout) && timeout.Timeout()
+}
@@ -0,0 +1,155 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	cfg "github.com/ibp-network/ibp-geodns-libs/config"
+
+	"github.com/gorilla/websocket"
+)
+
+// newHeadsServer answers chain_subscribeNewHeads with one notification per
+// entry of blocks, then confirms the unsubscribe.
+func newHeadsServer(t *testing.T, blocks []int64, interval time.Duration) *websocket.Conn {
+	t.Helper()
+
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		for {
+			var req JSONRPCRequest
+			if err := c.ReadJSON(&req); err != nil {
+				return
+			}
+			switch req.Method {
+			case "chain_subscribeNewHeads":
+				_ = c.WriteJSON(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": "sub-1"})
+				for _, b := range blocks {
+					time.Sleep(interval)
+					_ = c.WriteJSON(map[string]interface{}{
+						"jsonrpc": "2.0",
+						"method":  "chain_newHead",
+						"params": map[string]interface{}{
+							"subscription": "sub-1",
+							"result":       map[string]interface{}{"number": fmt.Sprintf("0x%x", b)},
+						},
+					})
+				}
+			case "chain_unsubscribeNewHeads":
+				_ = c.WriteJSON(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": true})
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	c, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial test server: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func testSubscriptionOptions() subscriptionOptions {
+	return subscriptionOptions{
+		Notifications: 3,
+		Deadline:      time.Now().Add(2 * time.Second),
+		MaxStall:      500 * time.Millisecond,
+		ReadTimeout:   time.Second,
+	}
+}
+
+func sequentialIDs() func() int {
+	id := 0
+	return func() int {
+		id++
+		return id
+	}
+}
+
+func TestRunHeadSubscriptionReceivesHeadsAndUnsubscribes(t *testing.T) {
+	c := newHeadsServer(t, []int64{100, 101, 101, 102}, 10*time.Millisecond)
+
+	stats, err := runHeadSubscription(c, headSubscriptions["newHeads"], testSubscriptionOptions(), sequentialIDs())
+	if err != nil {
+		t.Fatalf("runHeadSubscription returned error: %v", err)
+	}
+	if stats.Notifications != 3 || stats.FirstBlock != 100 || stats.LastBlock != 101 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if len(stats.Intervals) != 2 || !stats.Unsubscribed {
+		t.Fatalf("expected two intervals and a clean unsubscribe, got %+v", stats)
+	}
+
+	data := subscriptionData(stats, nil)
+	if _, ok := data["AvgIntervalMs"]; !ok {
+		t.Fatalf("expected interval data, got %#v", data)
+	}
+	if _, err := json.Marshal(data); err != nil {
+		t.Fatalf("subscription data must be JSON encodable: %v", err)
+	}
+}
+
+func TestRunHeadSubscriptionReportsStall(t *testing.T) {
+	c := newHeadsServer(t, []int64{100}, 10*time.Millisecond)
+
+	stats, err := runHeadSubscription(c, headSubscriptions["newHeads"], testSubscriptionOptions(), sequentialIDs())
+	if err == nil || !strings.Contains(err.Error(), "stalled after 1 of 3") {
+		t.Fatalf("expected stall error, got %v", err)
+	}
+	if stats.Unsubscribed {
+		t.Fatalf("stalled subscription should not report a clean unsubscribe")
+	}
+}
+
+func TestRunHeadSubscriptionStopsAtSharedBudget(t *testing.T) {
+	c := newHeadsServer(t, []int64{100, 101, 102}, 300*time.Millisecond)
+
+	opts := testSubscriptionOptions()
+	opts.Deadline = time.Now().Add(450 * time.Millisecond)
+	stats, err := runHeadSubscription(c, headSubscriptions["newHeads"], opts, sequentialIDs())
+	if err == nil || !strings.Contains(err.Error(), "budget used up after 1 of 3") {
+		t.Fatalf("expected budget error, got %v (stats %+v)", err, stats)
+	}
+}
+
+func TestSubscriptionOptionsBudgetEndsWithCheckDeadline(t *testing.T) {
+	now := time.Now()
+	check := cfg.Check{ExtraOptions: map[string]interface{}{"Budget": 60}}
+
+	if got := subscriptionOptionsFor(context.Background(), check, now).Deadline; !got.Equal(now.Add(60 * time.Second)) {
+		t.Fatalf("expected the budget to end 60s from now, got %v", got.Sub(now))
+	}
+
+	ctx, cancel := context.WithDeadline(context.Background(), now.Add(10*time.Second))
+	defer cancel()
+	if got := subscriptionOptionsFor(ctx, check, now).Deadline; !got.Equal(now.Add(10 * time.Second)) {
+		t.Fatalf("expected the budget to end with the check, got %v", got.Sub(now))
+	}
+}
+
+func TestRunHeadSubscriptionRejectsBackwardsBlocks(t *testing.T) {
+	c := newHeadsServer(t, []int64{100, 99, 101}, 10*time.Millisecond)
+
+	if _, err := runHeadSubscription(c, headSubscriptions["newHeads"], testSubscriptionOptions(), sequentialIDs()); err == nil ||
+		!strings.Contains(err.Error(), "went backwards") {
+		t.Fatalf("expected backwards block error, got %v", err)
+	}
+}
+
+func TestSubscriptionsFromOptionsRejectsUnknownName(t *testing.T) {
+	if _, err := subscriptionsFromOptions(map[string]interface{}{"Subscriptions": []interface{}{"allHeads"}}); err == nil {
+		t.Fatalf("expected unknown subscription to fail")
+	}
+}
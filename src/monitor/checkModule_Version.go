@@ -0,0 +1,220 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	cfg "github.com/ibp-network/ibp-geodns-libs/config"
+	log "github.com/ibp-network/ibp-geodns-libs/logging"
+)
+
+func init() {
+	// Version check flags members behind a runtime upgrade or on an old client
+	RegisterEndpointCheckWithTypes("version", VersionCheck, []string{"RPC"})
+}
+
+var specVersions = newObservationBoard[int64]()
+
+// majoritySpecVersion returns the specVersion reported most often, preferring
+// the higher version on a tie.
+func majoritySpecVersion(versions map[string]int64) int64 {
+	counts := make(map[int64]int)
+	for _, v := range versions {
+		counts[v]++
+	}
+
+	var majority int64
+	best := 0
+	for v, n := range counts {
+		if n > best || n == best && v > majority {
+			majority, best = v, n
+		}
+	}
+	return majority
+}
+
+func VersionCheck(ctx context.Context, check cfg.Check, endpoint string, service cfg.Service, member cfg.Member) {
+	target, err := parseCheckTarget(endpoint, "wss")
+	if err != nil {
+		UpdateEndpointResultLocal(check, member, service, endpoint, false, fmt.Sprintf("Invalid WebSocket target: %v", err), nil, false)
+		return
+	}
+	target.Scheme = websocketSchemeForTarget(target.Scheme)
+	target.URL = buildTargetURL(target.Scheme, target.Hostname, target.Port, target.RequestURI)
+
+	minClient, err := minClientVersion(check.ExtraOptions, service.Configuration.NetworkName)
+	if err != nil {
+		UpdateEndpointResultLocal(check, member, service, endpoint, false, err.Error(), nil, false)
+		return
+	}
+
+	ip4 := member.Service.ServiceIPv4
+	ip6 := member.Service.ServiceIPv6
+	if ip4 == "" && ip6 == "" {
+		UpdateEndpointResultLocal(check, member, service, endpoint, false, "No IPv4 or IPv6 configured", nil, false)
+		return
+	}
+
+	if ip4 != "" {
+		runVersionSingle(ctx, check, endpoint, target, service, member, ip4, false, minClient)
+	}
+	if ip6 != "" {
+		runVersionSingle(ctx, check, endpoint, target, service, member, ip6, true, minClient)
+	}
+}
+
+// minClientVersion returns the MinClientVersion listed for network, or "" when
+// the network is not listed. Client versioning differs between chains, so the
+// option maps network names to versions, e.g. {"Polkadot": "1.16.0"}.
+func minClientVersion(extraOptions map[string]interface{}, network string) (string, error) {
+	raw, ok := extraOptions["MinClientVersion"]
+	if !ok || raw == nil {
+		return "", nil
+	}
+	byNetwork, ok := raw.(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("MinClientVersion must map network names to versions")
+	}
+	for name, v := range byNetwork {
+		if !strings.EqualFold(name, network) {
+			continue
+		}
+		version, _ := v.(string)
+		if _, ok := parseSemver(version); !ok {
+			return "", fmt.Errorf("invalid MinClientVersion %v for %s", v, name)
+		}
+		return version, nil
+	}
+	return "", nil
+}
+
+func runVersionSingle(ctx context.Context, check cfg.Check, endpoint string, target CheckTarget, service cfg.Service, member cfg.Member, ip string, isIPv6 bool, minClient string) {
+	c, err := dialWebsocket(ctx, check, target, ip)
+	if err != nil {
+		UpdateEndpointResultLocal(check, member, service, endpoint, false,
+			checkFailureText(ctx, check, fmt.Sprintf("Failed to connect on IP=%s => %v", ip, err)), nil, isIPv6)
+		return
+	}
+	defer c.Close()
+
+	stopClose := context.AfterFunc(ctx, func() { _ = c.Close() })
+	defer stopClose()
+
+	call := wsSubstrateCall(c, getIntOption(check.ExtraOptions, "ReadTimeout", 15))
+
+	var runtime struct {
+		SpecName    string `json:"specName"`
+		SpecVersion int64  `json:"specVersion"`
+	}
+	if err := call("state_getRuntimeVersion", nil, "state_getRuntimeVersion", &runtime); err != nil {
+		UpdateEndpointResultLocal(check, member, service, endpoint, false, checkFailureText(ctx, check, err.Error()), nil, isIPv6)
+		return
+	}
+	var clientVersion string
+	if err := call("system_version", nil, "system_version", &clientVersion); err != nil {
+		UpdateEndpointResultLocal(check, member, service, endpoint, false, checkFailureText(ctx, check, err.Error()), nil, isIPv6)
+		return
+	}
+
+	network, source := observationScope(service, member, target, ip)
+	versions := specVersions.record(network, source, runtime.SpecVersion, time.Now(), observationWindow(check))
+	majority, sources := majoritySpecVersion(versions), observationMembers(versions)
+
+	dataMap := map[string]interface{}{
+		"SpecName":            runtime.SpecName,
+		"SpecVersion":         runtime.SpecVersion,
+		"MajoritySpecVersion": majority,
+		"MajoritySources":     sources,
+		"ClientVersion":       clientVersion,
+	}
+
+	// With too few members observed the majority is mostly the endpoint's own
+	// version, so it is only enforced from MinSources members on.
+	minSources := observationMinSources(check)
+	required := int64(getIntOption(check.ExtraOptions, "MinSpecVersion", 0))
+	if required <= 0 && sources >= minSources {
+		required = majority
+	}
+
+	errText := versionFailure(runtime.SpecVersion, required, clientVersion, minClient, dataMap)
+	success := errText == ""
+
+	if success && required <= 0 {
+		reason := fmt.Sprintf("Majority specVersion from %d member(s), MinSources %d", sources, minSources)
+		UpdateEndpointResultInconclusive(check, member, service, endpoint, reason, dataMap, isIPv6)
+		log.Log(log.Debug, "Version check inconclusive for %s %s isIPv6=%v: %s", member.Details.Name, endpoint, isIPv6, reason)
+		return
+	}
+
+	UpdateEndpointResultLocal(check, member, service, endpoint, success, errText, dataMap, isIPv6)
+	log.Log(log.Debug, "Version check completed for %s %s isIPv6=%v success=%v spec=%d client=%s",
+		member.Details.Name, endpoint, isIPv6, success, runtime.SpecVersion, clientVersion)
+}
+
+// versionFailure compares the observed versions with their requirements,
+// recording a pass flag for each in dataMap. A required specVersion of 0 skips
+// the spec check; an empty minClient skips the client check.
+func versionFailure(spec, required int64, clientVersion, minClient string, dataMap map[string]interface{}) string {
+	var failures []string
+
+	if required > 0 {
+		dataMap["RequiredSpecVersion"] = required
+		specOK := spec >= required
+		dataMap["SpecVersionOK"] = specOK
+		if !specOK {
+			failures = append(failures, fmt.Sprintf("Runtime specVersion %d behind %d", spec, required))
+		}
+	}
+
+	if minClient != "" {
+		want, _ := parseSemver(minClient)
+		have, ok := parseSemver(clientVersion)
+		clientOK := ok && compareSemver(have, want) >= 0
+		dataMap["MinClientVersion"] = minClient
+		dataMap["ClientVersionOK"] = clientOK
+		if !clientOK {
+			failures = append(failures, fmt.Sprintf("Client version %q below %s", clientVersion, minClient))
+		}
+	}
+
+	return strings.Join(failures, "; ")
+}
+
+var semverPattern = regexp.MustCompile(`^v?(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// parseSemver reads the leading major.minor[.patch] of a client version such
+// as "1.16.1-0a8a4d6a3b2".
+func parseSemver(raw string) ([3]int, bool) {
+	m := semverPattern.FindStringSubmatch(strings.TrimSpace(raw))
+	if m == nil {
+		return [3]int{}, false
+	}
+	var out [3]int
+	for i := 0; i < 3; i++ {
+		if m[i+1] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(m[i+1])
+		if err != nil {
+			return [3]int{}, false
+		}
+		out[i] = n
+	}
+	return out, true
+}
+
+func compareSemver(a, b [3]int) int {
+	for i := 0; i < 3; i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
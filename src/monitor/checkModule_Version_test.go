@@ -0,0 +1,73 @@
+package monitor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMajoritySpecVersion(t *testing.T) {
+	if got := majoritySpecVersion(map[string]int64{"a": 1003000, "b": 1003000, "c": 1002000}); got != 1003000 {
+		t.Fatalf("expected majority 1003000, got %d", got)
+	}
+	if got := majoritySpecVersion(map[string]int64{"a": 9000, "b": 9100}); got != 9100 {
+		t.Fatalf("expected tie to resolve to 9100, got %d", got)
+	}
+}
+
+func TestVersionFailure(t *testing.T) {
+	data := map[string]interface{}{}
+	if got := versionFailure(1003000, 1003000, "1.16.1-0a8a4d6a3b2", "1.15.0", data); got != "" {
+		t.Fatalf("expected pass, got %q", got)
+	}
+	if data["SpecVersionOK"] != true || data["ClientVersionOK"] != true {
+		t.Fatalf("unexpected flags %#v", data)
+	}
+
+	data = map[string]interface{}{}
+	got := versionFailure(1003000, 1004000, "0.9.42-9b1fc27cec4", "1.0", data)
+	if !strings.Contains(got, "behind 1004000") || !strings.Contains(got, "below 1.0") {
+		t.Fatalf("expected spec and client failures, got %q", got)
+	}
+
+	data = map[string]interface{}{}
+	if got := versionFailure(1003000, 0, "1.16.1", "", data); got != "" {
+		t.Fatalf("expected no requirements to pass, got %q", got)
+	}
+	if _, ok := data["SpecVersionOK"]; ok {
+		t.Fatalf("expected the spec check to be skipped without a requirement, got %#v", data)
+	}
+}
+
+func TestMinClientVersionIsKeyedByNetwork(t *testing.T) {
+	opts := map[string]interface{}{"MinClientVersion": map[string]interface{}{"Polkadot": "1.16.0"}}
+	if got, err := minClientVersion(opts, "polkadot"); err != nil || got != "1.16.0" {
+		t.Fatalf("expected 1.16.0 for polkadot, got %q %v", got, err)
+	}
+	if got, err := minClientVersion(opts, "moonbeam"); err != nil || got != "" {
+		t.Fatalf("expected unlisted network to be skipped, got %q %v", got, err)
+	}
+	if _, err := minClientVersion(map[string]interface{}{"MinClientVersion": "1.16.0"}, "polkadot"); err == nil {
+		t.Fatalf("expected a single global version to be rejected")
+	}
+	bad := map[string]interface{}{"MinClientVersion": map[string]interface{}{"polkadot": "latest"}}
+	if _, err := minClientVersion(bad, "polkadot"); err == nil {
+		t.Fatalf("expected an invalid version to be rejected")
+	}
+}
+
+func TestParseSemver(t *testing.T) {
+	cases := map[string][3]int{
+		"1.16.1-0a8a4d6a3b2": {1, 16, 1},
+		"v2.0":               {2, 0, 0},
+		"0.9.42-9b1fc27cec4": {0, 9, 42},
+	}
+	for in, want := range cases {
+		got, ok := parseSemver(in)
+		if !ok || got != want {
+			t.Fatalf("parseSemver(%q) = %v %v, want %v", in, got, ok, want)
+		}
+	}
+	if _, ok := parseSemver("parity-polkadot"); ok {
+		t.Fatalf("expected non-version string to fail")
+	}
+}
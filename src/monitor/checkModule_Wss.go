@@ -1,6 +1,7 @@
 package monitor
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
@@ -36,7 +37,7 @@ func init() {
 	RegisterEndpointCheckWithTypes("wss", WssCheck, []string{"RPC"})
 }
 
-func WssCheck(check cfg.Check, endpoint string, service cfg.Service, member cfg.Member) {
+func WssCheck(ctx context.Context, check cfg.Check, endpoint string, service cfg.Service, member cfg.Member) {
 	target, err := parseCheckTarget(endpoint, "wss")
 	if err != nil {
 		UpdateEndpointResultLocal(check, member, service, endpoint, false, fmt.Sprintf("Invalid WebSocket target: %v", err), nil, false)
@@ -55,100 +56,130 @@ func WssCheck(check cfg.Check, endpoint string, service cfg.Service, member cfg.
 	}
 
 	if ip4 != "" {
-		runWssSingle(check, endpoint, target, service, member, ip4, false, readTimeoutSec)
+		runWssSingle(ctx, check, endpoint, target, service, member, ip4, false, readTimeoutSec)
 	}
 
 	if ip6 != "" {
-		runWssSingle(check, endpoint, target, service, member, ip6, true, readTimeoutSec)
+		runWssSingle(ctx, check, endpoint, target, service, member, ip6, true, readTimeoutSec)
 	}
 }
 
-func runWssSingle(check cfg.Check, endpoint string, target CheckTarget, service cfg.Service, member cfg.Member, ip string, isIPv6 bool, readTimeoutSec int) {
+func runWssSingle(ctx context.Context, check cfg.Check, endpoint string, target CheckTarget, service cfg.Service, member cfg.Member, ip string, isIPv6 bool, readTimeoutSec int) {
+	c, err := dialWebsocket(ctx, check, target, ip)
+	if err != nil {
+		UpdateEndpointResultLocal(check, member, service, endpoint, false,
+			checkFailureText(ctx, check, fmt.Sprintf("Failed to connect on IP=%s => %v", ip, err)), nil, isIPv6)
+		log.Log(log.Debug, "WSS check failed for %s %s isIPv6=%v success=%v", member.Details.Name, endpoint, isIPv6, false)
+		return
+	}
+	defer c.Close()
+
+	// Closing the connection unblocks any pending read once the check's budget is spent.
+	stopClose := context.AfterFunc(ctx, func() { _ = c.Close() })
+	defer stopClose()
+
+	runSubstrateChecks(ctx, check, endpoint, service, member, isIPv6, "WSS", wsSubstrateCall(c, readTimeoutSec))
+}
+
+// dialWebsocket opens a WebSocket to target through ip, keeping the target
+// hostname for SNI and certificate validation.
+func dialWebsocket(ctx context.Context, check cfg.Check, target CheckTarget, ip string) (*websocket.Conn, error) {
+	connectTimeout := time.Duration(getIntOption(check.ExtraOptions, "ConnectTimeout", 10)) * time.Second
 	dialer := websocket.Dialer{
 		TLSClientConfig: &tls.Config{
 			ServerName:         target.Hostname,
 			InsecureSkipVerify: false,
 		},
-		NetDial: func(network, addr string) (net.Conn, error) {
-			return net.DialTimeout(network, target.DialAddress(ip),
-				time.Duration(getIntOption(check.ExtraOptions, "ConnectTimeout", 10))*time.Second)
+		NetDialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			d := net.Dialer{Timeout: connectTimeout}
+			return d.DialContext(ctx, network, target.DialAddress(ip))
 		},
-		HandshakeTimeout: time.Duration(getIntOption(check.ExtraOptions, "ConnectTimeout", 10)) * time.Second,
+		HandshakeTimeout: connectTimeout,
 	}
 
-	c, _, err := dialer.Dial(target.URL, nil)
-	if err != nil {
-		UpdateEndpointResultLocal(check, member, service, endpoint, false, fmt.Sprintf("Failed to connect on IP=%s => %v", ip, err), nil, isIPv6)
-		log.Log(log.Debug, "WSS check failed for %s %s isIPv6=%v success=%v", member.Details.Name, endpoint, isIPv6, false)
-		return
-	}
-	defer c.Close()
+	c, _, err := dialer.DialContext(ctx, target.URL, nil)
+	return c, err
+}
 
-	request := JSONRPCRequest{
-		JSONRPC: "2.0",
-		Method:  "chain_getBlockHash",
-		Params:  []interface{}{},
-		ID:      1,
-	}
+// substrateCall issues one Substrate JSON-RPC call and decodes its result into
+// target. The WSS and HTTP RPC checks differ only in how they provide it.
+type substrateCall func(method string, params []interface{}, desc string, target interface{}) error
 
-	if !sendJSONRPCRequest(c, request) {
-		UpdateEndpointResultLocal(check, member, service, endpoint, false, "Failed to send JSON RPC", nil, isIPv6)
-		log.Log(log.Debug, "WSS check failed for %s %s isIPv6=%v success=%v", member.Details.Name, endpoint, isIPv6, false)
-		return
+func wsSubstrateCall(c *websocket.Conn, readTimeoutSec int) substrateCall {
+	id := 0
+	return func(method string, params []interface{}, desc string, target interface{}) error {
+		id++
+		if params == nil {
+			params = []interface{}{}
+		}
+		request := JSONRPCRequest{
+			JSONRPC: "2.0",
+			Method:  method,
+			Params:  params,
+			ID:      id,
+		}
+		if !sendJSONRPCRequest(c, request) {
+			return fmt.Errorf("%s: failed to send request", desc)
+		}
+		return readJSONRPCResult(c, readTimeoutSec, desc, target)
 	}
+}
 
+// runSubstrateChecks runs the block hash, archive, network and peer checks
+// over call and records the endpoint result. label names the transport in logs.
+func runSubstrateChecks(ctx context.Context, check cfg.Check, endpoint string, service cfg.Service, member cfg.Member, isIPv6 bool, label string, call substrateCall) {
 	var latestBlockHash string
-	if err := readJSONRPCResult(c, readTimeoutSec, "chain_getBlockHash()", &latestBlockHash); err != nil || latestBlockHash == "" {
+	if err := call("chain_getBlockHash", nil, "chain_getBlockHash()", &latestBlockHash); err != nil || latestBlockHash == "" {
 		errText := "chain_getBlockHash() returned an empty result"
 		if err != nil {
 			errText = err.Error()
 		}
-		UpdateEndpointResultLocal(check, member, service, endpoint, false, errText, nil, isIPv6)
-		log.Log(log.Debug, "WSS check failed for %s %s isIPv6=%v success=%v", member.Details.Name, endpoint, isIPv6, false)
+		UpdateEndpointResultLocal(check, member, service, endpoint, false, checkFailureText(ctx, check, errText), nil, isIPv6)
+		log.Log(log.Debug, "%s check failed for %s %s isIPv6=%v success=%v", label, member.Details.Name, endpoint, isIPv6, false)
 		return
 	}
 
-	isFullArchive, err := checkFullArchive(c, readTimeoutSec)
+	isFullArchive, err := checkFullArchive(call)
 	if err != nil {
-		UpdateEndpointResultLocal(check, member, service, endpoint, false, fmt.Sprintf("Full archive check failed: %v", err), nil, isIPv6)
-		log.Log(log.Debug, "WSS check failed for %s %s isIPv6=%v success=%v", member.Details.Name, endpoint, isIPv6, false)
+		UpdateEndpointResultLocal(check, member, service, endpoint, false, checkFailureText(ctx, check, fmt.Sprintf("Full archive check failed: %v", err)), nil, isIPv6)
+		log.Log(log.Debug, "%s check failed for %s %s isIPv6=%v success=%v", label, member.Details.Name, endpoint, isIPv6, false)
 		return
 	}
 
 	if !isFullArchive {
 		UpdateEndpointResultLocal(check, member, service, endpoint, false, "Not a full archive node", nil, isIPv6)
-		log.Log(log.Debug, "WSS check failed for %s %s isIPv6=%v success=%v", member.Details.Name, endpoint, isIPv6, false)
+		log.Log(log.Debug, "%s check failed for %s %s isIPv6=%v success=%v", label, member.Details.Name, endpoint, isIPv6, false)
 		return
 	}
 
-	isCorrectNetwork, err := checkNetwork(c, service.Configuration.NetworkName, service.Configuration.StateRootHash, readTimeoutSec)
+	isCorrectNetwork, err := checkNetwork(call, service.Configuration.NetworkName, service.Configuration.StateRootHash)
 	if err != nil {
-		UpdateEndpointResultLocal(check, member, service, endpoint, false, fmt.Sprintf("Network check failed: %v", err), nil, isIPv6)
-		log.Log(log.Debug, "WSS check failed for %s %s isIPv6=%v success=%v", member.Details.Name, endpoint, isIPv6, false)
+		UpdateEndpointResultLocal(check, member, service, endpoint, false, checkFailureText(ctx, check, fmt.Sprintf("Network check failed: %v", err)), nil, isIPv6)
+		log.Log(log.Debug, "%s check failed for %s %s isIPv6=%v success=%v", label, member.Details.Name, endpoint, isIPv6, false)
 		return
 	}
 
 	if !isCorrectNetwork {
 		UpdateEndpointResultLocal(check, member, service, endpoint, false, "Wrong network", nil, isIPv6)
-		log.Log(log.Debug, "WSS check failed for %s %s isIPv6=%v success=%v", member.Details.Name, endpoint, isIPv6, false)
+		log.Log(log.Debug, "%s check failed for %s %s isIPv6=%v success=%v", label, member.Details.Name, endpoint, isIPv6, false)
 		return
 	}
 
 	minPeers := getIntOption(check.ExtraOptions, "MinimumPeers", 5)
-	hasEnoughPeers, isSyncing, peerCount, err := checkPeers(c, readTimeoutSec, minPeers)
+	hasEnoughPeers, isSyncing, peerCount, err := checkPeers(call, minPeers)
 	if err != nil {
-		UpdateEndpointResultLocal(check, member, service, endpoint, false, fmt.Sprintf("Peer check failed: %v", err), nil, isIPv6)
-		log.Log(log.Debug, "WSS check failed for %s %s isIPv6=%v success=%v", member.Details.Name, endpoint, isIPv6, false)
+		UpdateEndpointResultLocal(check, member, service, endpoint, false, checkFailureText(ctx, check, fmt.Sprintf("Peer check failed: %v", err)), nil, isIPv6)
+		log.Log(log.Debug, "%s check failed for %s %s isIPv6=%v success=%v", label, member.Details.Name, endpoint, isIPv6, false)
 		return
 	}
 
 	if !hasEnoughPeers || isSyncing {
 		UpdateEndpointResultLocal(check, member, service, endpoint, false, "Syncing or not enough peers", nil, isIPv6)
-		log.Log(log.Debug, "WSS check failed for %s %s isIPv6=%v success=%v", member.Details.Name, endpoint, isIPv6, false)
+		log.Log(log.Debug, "%s check failed for %s %s isIPv6=%v success=%v", label, member.Details.Name, endpoint, isIPv6, false)
 		return
 	}
 
-	log.Log(log.Debug, "WSS check completed for %s %s isIPv6=%v success=%v", member.Details.Name, endpoint, isIPv6, true)
+	log.Log(log.Debug, "%s check completed for %s %s isIPv6=%v success=%v", label, member.Details.Name, endpoint, isIPv6, true)
 	UpdateEndpointResultLocal(check, member, service, endpoint, true, "",
 		map[string]interface{}{
 			"Syncing":   isSyncing,
@@ -159,21 +190,9 @@ func runWssSingle(check cfg.Check, endpoint string, target CheckTarget, service
 		}, isIPv6)
 }
 
-// The rest is unchanged
-func checkFullArchive(c *websocket.Conn, readTimeoutSec int) (bool, error) {
-	req := JSONRPCRequest{
-		JSONRPC: "2.0",
-		Method:  "chain_getBlockHash",
-		Params:  []interface{}{0},
-		ID:      2,
-	}
-
-	if !sendJSONRPCRequest(c, req) {
-		return false, fmt.Errorf("failed to send blockHash(0) request")
-	}
-
+func checkFullArchive(call substrateCall) (bool, error) {
 	var result string
-	if err := readJSONRPCResult(c, readTimeoutSec, "chain_getBlockHash(0)", &result); err != nil {
+	if err := call("chain_getBlockHash", []interface{}{0}, "chain_getBlockHash(0)", &result); err != nil {
 		return false, err
 	}
 
@@ -184,20 +203,10 @@ func checkFullArchive(c *websocket.Conn, readTimeoutSec int) (bool, error) {
 	return true, nil
 }
 
-func checkNetwork(c *websocket.Conn, expectedNetwork string, expectedStateRootHash string, readTimeoutSec int) (bool, error) {
+func checkNetwork(call substrateCall, expectedNetwork string, expectedStateRootHash string) (bool, error) {
 	// First check the chain name
-	req := JSONRPCRequest{
-		JSONRPC: "2.0",
-		Method:  "system_chain",
-		ID:      3,
-	}
-
-	if !sendJSONRPCRequest(c, req) {
-		return false, fmt.Errorf("failed to send system_chain request")
-	}
-
 	var chain string
-	if err := readJSONRPCResult(c, readTimeoutSec, "system_chain", &chain); err != nil {
+	if err := call("system_chain", nil, "system_chain", &chain); err != nil {
 		return false, err
 	}
 
@@ -213,40 +222,17 @@ func checkNetwork(c *websocket.Conn, expectedNetwork string, expectedStateRootHa
 
 	// Check the state root hash of the genesis block (block 0)
 	// Get block hash at height 0
-	blockHashReq := JSONRPCRequest{
-		JSONRPC: "2.0",
-		Method:  "chain_getBlockHash",
-		Params:  []interface{}{0},
-		ID:      4,
-	}
-
-	if !sendJSONRPCRequest(c, blockHashReq) {
-		return false, fmt.Errorf("failed to send chain_getBlockHash(0) for genesis block")
-	}
-
 	var genesisBlockHash string
-	if err := readJSONRPCResult(c, readTimeoutSec, "chain_getBlockHash(0)", &genesisBlockHash); err != nil {
+	if err := call("chain_getBlockHash", []interface{}{0}, "chain_getBlockHash(0)", &genesisBlockHash); err != nil {
 		return false, fmt.Errorf("failed to read genesis block hash response: %v", err)
 	}
 	if genesisBlockHash == "" {
 		return false, fmt.Errorf("invalid genesis block hash response")
 	}
 
-	// Get genesis block header to extract state root
-	headerReq := JSONRPCRequest{
-		JSONRPC: "2.0",
-		Method:  "chain_getHeader",
-		Params:  []interface{}{genesisBlockHash},
-		ID:      5,
-	}
-
-	if !sendJSONRPCRequest(c, headerReq) {
-		return false, fmt.Errorf("failed to send chain_getHeader request for genesis block")
-	}
-
 	// Extract state root from genesis block header
 	header := make(map[string]interface{})
-	if err := readJSONRPCResult(c, readTimeoutSec, "chain_getHeader(genesis)", &header); err != nil {
+	if err := call("chain_getHeader", []interface{}{genesisBlockHash}, "chain_getHeader(genesis)", &header); err != nil {
 		return false, fmt.Errorf("failed to read genesis header response: %v", err)
 	}
 
@@ -265,19 +251,9 @@ func checkNetwork(c *websocket.Conn, expectedNetwork string, expectedStateRootHa
 	return true, nil
 }
 
-func checkPeers(c *websocket.Conn, readTimeoutSec int, minPeers int) (bool, bool, int64, error) {
-	req := JSONRPCRequest{
-		JSONRPC: "2.0",
-		Method:  "system_health",
-		ID:      6,
-	}
-
-	if !sendJSONRPCRequest(c, req) {
-		return false, false, 0, fmt.Errorf("failed to send system_health request")
-	}
-
+func checkPeers(call substrateCall, minPeers int) (bool, bool, int64, error) {
 	result := make(map[string]interface{})
-	if err := readJSONRPCResult(c, readTimeoutSec, "system_health", &result); err != nil {
+	if err := call("system_health", nil, "system_health", &result); err != nil {
 		return false, false, 0, err
 	}
 
@@ -1,7 +1,12 @@
 package monitor
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"strings"
+	"sync"
+	"time"
 
 	cfg "github.com/ibp-network/ibp-geodns-libs/config"
 	dat "github.com/ibp-network/ibp-geodns-libs/data"
@@ -19,9 +24,9 @@ var CheckRegistry = struct {
 }
 
 type (
-	CheckSiteFunc     func(check cfg.Check, member cfg.Member)
-	CheckDomainFunc   func(check cfg.Check, domain string, service cfg.Service, member cfg.Member)
-	CheckEndpointFunc func(check cfg.Check, endpoint string, service cfg.Service, member cfg.Member)
+	CheckSiteFunc     func(ctx context.Context, check cfg.Check, member cfg.Member)
+	CheckDomainFunc   func(ctx context.Context, check cfg.Check, domain string, service cfg.Service, member cfg.Member)
+	CheckEndpointFunc func(ctx context.Context, check cfg.Check, endpoint string, service cfg.Service, member cfg.Member)
 )
 
 // ServiceTypeValidator holds service type validation info for checks
@@ -97,57 +102,209 @@ func getEndpointCheck(name string) (CheckEndpointFunc, bool) {
 	return fn, ok
 }
 
+// checkContext bounds a single check run by the check's Timeout (seconds).
+// A zero or negative Timeout leaves the context without a deadline.
+func checkContext(parent context.Context, check cfg.Check) (context.Context, context.CancelFunc) {
+	if check.Timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, time.Duration(check.Timeout)*time.Second)
+}
+
+// isCheckTimeout reports whether ctx ended because the check's Timeout elapsed.
+func isCheckTimeout(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
+
+// checkFailureText replaces errText with a timeout reason when the check's
+// budget ran out, so a cancelled dial or read isn't reported as a connect error.
+func checkFailureText(ctx context.Context, check cfg.Check, errText string) string {
+	if isCheckTimeout(ctx) {
+		return fmt.Sprintf("Check timed out after %ds", check.Timeout)
+	}
+	return errText
+}
+
+var (
+	resultListenersMu sync.RWMutex
+	resultListeners   = make(map[int]func(CheckResult))
+	nextListenerID    int
+)
+
+// AddResultListener registers fn to receive every local check result. The
+// returned function removes the listener again.
+func AddResultListener(fn func(CheckResult)) func() {
+	resultListenersMu.Lock()
+	defer resultListenersMu.Unlock()
+
+	nextListenerID++
+	id := nextListenerID
+	resultListeners[id] = fn
+
+	return func() {
+		resultListenersMu.Lock()
+		defer resultListenersMu.Unlock()
+		delete(resultListeners, id)
+	}
+}
+
+// runMarks holds, per running check target, when the current measurement began.
+// Each recorded result closes one measurement and starts the next, so a check
+// that reports IPv4 then IPv6 gets a separate duration for each family.
+var runMarks sync.Map
+
+// resultClock stamps results and measures their durations; replaced in tests.
+var resultClock clock = realClock{}
+
+func resultKey(checkType, checkName, member, domain, endpoint string) string {
+	return checkType + "|" + checkName + "|" + member + "|" + domain + "|" + endpoint
+}
+
+func markRunStart(item *CheckItem) (key string) {
+	key = resultKey(item.Type, item.Check.Name, item.Member.Details.Name, item.Domain, item.Endpoint)
+	runMarks.Store(key, resultClock.Now())
+	return key
+}
+
+func takeRunDuration(key string, now time.Time) time.Duration {
+	v, ok := runMarks.Load(key)
+	if !ok {
+		return 0
+	}
+	runMarks.Store(key, now)
+	return now.Sub(v.(time.Time))
+}
+
+func notifyResultListeners(r CheckResult) {
+	r.Checktime = resultClock.Now()
+	r.Duration = takeRunDuration(resultKey(r.Type, r.CheckName, r.Member, r.Domain, r.Endpoint), r.Checktime)
+
+	resultListenersMu.RLock()
+	defer resultListenersMu.RUnlock()
+	for _, fn := range resultListeners {
+		fn(r)
+	}
+}
+
 func UpdateSiteResultLocal(check cfg.Check, member cfg.Member, status bool, errText string,
 	data map[string]interface{}, ipv6 bool) {
+	proposal, data := evaluateStatusChange(pendingProposal{checkType: "site", checkName: check.Name,
+		memberName: member.Details.Name, status: status, errText: errText, data: data, ipv6: ipv6})
 	dat.UpdateLocalSiteResult(check, member, status, errText, data, ipv6)
-	proposeIfStatusChanged("site", check.Name, member.Details.Name, "", "",
-		status, errText, data, ipv6)
+	notifyResultListeners(CheckResult{Type: "site", CheckName: check.Name, Member: member.Details.Name,
+		IsIPv6: ipv6, Status: status, ErrorText: errText, Data: data})
+	sendProposal(proposal)
 }
 
 func UpdateDomainResultLocal(check cfg.Check, domain string, service cfg.Service,
 	member cfg.Member, status bool, errText string, data map[string]interface{}, ipv6 bool) {
+	proposal, data := evaluateStatusChange(pendingProposal{checkType: "domain", checkName: check.Name,
+		memberName: member.Details.Name, domainName: domain, status: status, errText: errText, data: data, ipv6: ipv6})
 	dat.UpdateLocalDomainResult(check, member, service, domain, status, errText, data, ipv6)
-	proposeIfStatusChanged("domain", check.Name, member.Details.Name, domain, "",
-		status, errText, data, ipv6)
+	notifyResultListeners(CheckResult{Type: "domain", CheckName: check.Name, Member: member.Details.Name,
+		Service: service.Configuration.NetworkName, Domain: domain, IsIPv6: ipv6, Status: status, ErrorText: errText, Data: data})
+	sendProposal(proposal)
 }
 
 func UpdateEndpointResultLocal(check cfg.Check, member cfg.Member, service cfg.Service,
 	endpoint string, status bool, errText string, data map[string]interface{}, ipv6 bool) {
 	domain := parseUrlForDomain(endpoint)
+	proposal, data := evaluateStatusChange(pendingProposal{checkType: "endpoint", checkName: check.Name,
+		memberName: member.Details.Name, domainName: domain, endpoint: endpoint, status: status, errText: errText, data: data, ipv6: ipv6})
 	dat.UpdateLocalEndpointResult(check, member, service, domain, endpoint, status, errText, data, ipv6)
-	proposeIfStatusChanged("endpoint", check.Name, member.Details.Name, domain, endpoint,
-		status, errText, data, ipv6)
+	notifyResultListeners(CheckResult{Type: "endpoint", CheckName: check.Name, Member: member.Details.Name,
+		Service: service.Configuration.NetworkName, Domain: domain, Endpoint: endpoint, IsIPv6: ipv6, Status: status, ErrorText: errText, Data: data})
+	sendProposal(proposal)
+}
+
+// UpdateDomainResultInconclusive reports a domain check run that could not
+// reach a verdict. The previous local and official results stand: nothing is
+// stored or proposed, and listeners see a result marked Inconclusive.
+func UpdateDomainResultInconclusive(check cfg.Check, domain string, service cfg.Service,
+	member cfg.Member, reason string, data map[string]interface{}, ipv6 bool) {
+	notifyResultListeners(CheckResult{Type: "domain", CheckName: check.Name, Member: member.Details.Name,
+		Service: service.Configuration.NetworkName, Domain: domain, IsIPv6: ipv6, ErrorText: reason,
+		Data: inconclusiveData(data), Inconclusive: true})
+}
+
+// UpdateEndpointResultInconclusive is UpdateDomainResultInconclusive for endpoint checks.
+func UpdateEndpointResultInconclusive(check cfg.Check, member cfg.Member, service cfg.Service,
+	endpoint string, reason string, data map[string]interface{}, ipv6 bool) {
+	notifyResultListeners(CheckResult{Type: "endpoint", CheckName: check.Name, Member: member.Details.Name,
+		Service: service.Configuration.NetworkName, Domain: parseUrlForDomain(endpoint), Endpoint: endpoint,
+		IsIPv6: ipv6, ErrorText: reason, Data: inconclusiveData(data), Inconclusive: true})
+}
+
+func inconclusiveData(data map[string]interface{}) map[string]interface{} {
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	data["Inconclusive"] = true
+	return data
 }
 
-func proposeIfStatusChanged(checkType, checkName, memberName, domainName, endpoint string,
-	status bool, errText string, data map[string]interface{}, ipv6 bool) {
+// evaluateStatusChange decides whether a local result should be proposed. It
+// returns the proposal, or nil, and the Data to record locally, which includes
+// the confirmation attempts so far while a change is being confirmed.
+func evaluateStatusChange(p pendingProposal) (*pendingProposal, map[string]interface{}) {
+	if standalone.Load() {
+		return nil, p.data
+	}
+
 	var (
 		found bool
 		cur   bool
 	)
 
-	switch checkType {
+	switch p.checkType {
 	case "site":
-		found, cur = dat.GetOfficialSiteStatus(checkName, memberName, ipv6)
+		found, cur = dat.GetOfficialSiteStatus(p.checkName, p.memberName, p.ipv6)
 	case "domain":
-		found, cur = dat.GetOfficialDomainStatus(checkName, memberName, domainName, ipv6)
+		found, cur = dat.GetOfficialDomainStatus(p.checkName, p.memberName, p.domainName, p.ipv6)
 	case "endpoint":
-		found, cur = dat.GetOfficialEndpointStatus(checkName, memberName, domainName, endpoint, ipv6)
-	}
-
-	if !found || cur != status {
-		natsCommon.ProposeCheckStatus(
-			checkType,
-			checkName,
-			memberName,
-			domainName,
-			endpoint,
-			status,
-			errText,
-			data,
-			ipv6,
-		)
+		found, cur = dat.GetOfficialEndpointStatus(p.checkName, p.memberName, p.domainName, p.endpoint, p.ipv6)
+	}
+
+	changed := !found || cur != p.status
+	if v, ok := confirmSessions.Load(resultKey(p.checkType, p.checkName, p.memberName, p.domainName, p.endpoint)); ok {
+		return v.(*confirmation).observe(p, changed)
+	}
+	if !changed {
+		return nil, p.data
 	}
+	return &p, p.data
+}
+
+// sendProposal proposes p to NATS; a nil p is ignored.
+func sendProposal(p *pendingProposal) {
+	if p == nil {
+		return
+	}
+
+	proposalsSent.WithLabelValues(p.checkType, p.checkName).Inc()
+	natsCommon.ProposeCheckStatus(
+		p.checkType,
+		p.checkName,
+		p.memberName,
+		p.domainName,
+		p.endpoint,
+		p.status,
+		p.errText,
+		p.data,
+		p.ipv6,
+	)
+	notifyStatusListeners(StatusEvent{
+		Kind:      StatusEventProposal,
+		Type:      p.checkType,
+		CheckName: p.checkName,
+		Member:    p.memberName,
+		Domain:    p.domainName,
+		Endpoint:  p.endpoint,
+		IsIPv6:    p.ipv6,
+		Status:    p.status,
+		ErrorText: p.errText,
+		Data:      p.data,
+	})
 }
 
 func assignedToService(svcName string, m cfg.Member) bool {
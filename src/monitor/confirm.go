@@ -0,0 +1,178 @@
+package monitor
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	log "github.com/ibp-network/ibp-geodns-libs/logging"
+)
+
+// confirmSessions holds the confirmation state of items currently being run,
+// keyed by resultKey. evaluateStatusChange parks changed statuses here
+// instead of proposing them straight away.
+var confirmSessions sync.Map
+
+// confirmation tracks status changes observed while re-running one item until
+// they have been seen ConfirmFailures/ConfirmSuccesses times in a row.
+type confirmation struct {
+	mu               sync.Mutex
+	confirmFailures  int
+	confirmSuccesses int
+	attempt          int
+	pending          map[bool]*pendingProposal // keyed by isIPv6
+}
+
+type pendingProposal struct {
+	checkType  string
+	checkName  string
+	memberName string
+	domainName string
+	endpoint   string
+	status     bool
+	errText    string
+	data       map[string]interface{}
+	ipv6       bool
+	streak     int
+	attempts   []map[string]interface{}
+}
+
+func newConfirmation(item *CheckItem) *confirmation {
+	failures := getIntOption(item.Check.ExtraOptions, "ConfirmFailures", 1)
+	successes := getIntOption(item.Check.ExtraOptions, "ConfirmSuccesses", 1)
+	if failures <= 1 && successes <= 1 {
+		return nil
+	}
+	return &confirmation{
+		confirmFailures:  max(failures, 1),
+		confirmSuccesses: max(successes, 1),
+		pending:          make(map[bool]*pendingProposal),
+	}
+}
+
+func (c *confirmation) required(status bool) int {
+	if status {
+		return c.confirmSuccesses
+	}
+	return c.confirmFailures
+}
+
+func (c *confirmation) maxAttempts() int {
+	return max(c.confirmFailures, c.confirmSuccesses)
+}
+
+func (c *confirmation) startAttempt() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.attempt++
+}
+
+// observe records one result for a family. changed reports whether the result
+// differs from the official status. It returns the proposal to send once the
+// change has been seen often enough, or nil while it is still unconfirmed,
+// together with the Data to record locally: while a change is pending, that
+// carries the attempts so far under ConfirmAttempts.
+func (c *confirmation) observe(p pendingProposal, changed bool) (*pendingProposal, map[string]interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !changed {
+		if prev, ok := c.pending[p.ipv6]; ok {
+			log.Log(log.Debug, "Status change for %s/%s ipv6=%v not confirmed after %d attempt(s)",
+				prev.checkName, prev.memberName, prev.ipv6, len(prev.attempts))
+			unconfirmedChanges.WithLabelValues(prev.checkName, "recovered").Inc()
+		}
+		delete(c.pending, p.ipv6)
+		return nil, p.data
+	}
+
+	prev, ok := c.pending[p.ipv6]
+	if !ok || prev.status != p.status {
+		prev = &p
+		c.pending[p.ipv6] = prev
+	} else {
+		prev.errText = p.errText
+		prev.data = p.data
+	}
+	prev.streak++
+	prev.attempts = append(prev.attempts, map[string]interface{}{
+		"Attempt":   c.attempt,
+		"Status":    p.status,
+		"ErrorText": p.errText,
+		"Checktime": time.Now().UTC().Format(time.RFC3339),
+	})
+	data := withAttempts(prev.data, prev.attempts)
+
+	if prev.streak < c.required(p.status) {
+		return nil, data
+	}
+
+	delete(c.pending, p.ipv6)
+	confirmed := *prev
+	confirmed.data = data
+	return &confirmed, data
+}
+
+// withAttempts returns a copy of data with attempts under ConfirmAttempts.
+func withAttempts(data map[string]interface{}, attempts []map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(data)+1)
+	for k, v := range data {
+		out[k] = v
+	}
+	out["ConfirmAttempts"] = slices.Clone(attempts)
+	return out
+}
+
+// abandon drops the changes still pending when no attempts are left, such as
+// one family's change that started after the other's and ran out of attempts.
+// It returns how many were dropped.
+func (c *confirmation) abandon() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, p := range c.pending {
+		log.Log(log.Info, "Status change for %s/%s ipv6=%v to %v dropped: seen %d of %d time(s) within %d attempt(s)",
+			p.checkName, p.memberName, p.ipv6, p.status, p.streak, c.required(p.status), c.attempt)
+		unconfirmedChanges.WithLabelValues(p.checkName, "attempts").Inc()
+	}
+	dropped := len(c.pending)
+	clear(c.pending)
+	return dropped
+}
+
+// needsRetry reports whether an unconfirmed change is waiting for another attempt.
+func (c *confirmation) needsRetry() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.pending) > 0 && c.attempt < c.maxAttempts()
+}
+
+// runConfirmedCheck runs item and, while a status change is unconfirmed,
+// re-runs it after RetryDelay until the change is confirmed or goes away.
+func runConfirmedCheck(parent context.Context, item *CheckItem, run func()) {
+	conf := newConfirmation(item)
+	if conf == nil {
+		run()
+		return
+	}
+
+	key := resultKey(item.Type, item.Check.Name, item.Member.Details.Name, item.Domain, item.Endpoint)
+	confirmSessions.Store(key, conf)
+	defer confirmSessions.Delete(key)
+	defer conf.abandon()
+
+	retryDelay := time.Duration(getIntOption(item.Check.ExtraOptions, "RetryDelay", 2)) * time.Second
+
+	conf.startAttempt()
+	run()
+	for conf.needsRetry() {
+		select {
+		case <-parent.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+		conf.startAttempt()
+		run()
+	}
+}
@@ -0,0 +1,141 @@
+package monitor
+
+import (
+	"context"
+	"testing"
+
+	cfg "github.com/ibp-network/ibp-geodns-libs/config"
+)
+
+func TestConfirmationProposesAfterRequiredFailures(t *testing.T) {
+	conf := newConfirmation(&CheckItem{Check: cfg.Check{ExtraOptions: map[string]interface{}{"ConfirmFailures": 3}}})
+	if conf == nil {
+		t.Fatalf("expected confirmation to be enabled")
+	}
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		conf.startAttempt()
+		p, data := conf.observe(pendingProposal{checkName: "wss", status: false, errText: "down"}, true)
+		if p != nil {
+			t.Fatalf("expected attempt %d to stay unconfirmed", attempt)
+		}
+		if attempts, _ := data["ConfirmAttempts"].([]map[string]interface{}); len(attempts) != attempt {
+			t.Fatalf("expected %d attempt(s) in the local result data, got %#v", attempt, data["ConfirmAttempts"])
+		}
+		if !conf.needsRetry() {
+			t.Fatalf("expected a retry after attempt %d", attempt)
+		}
+	}
+
+	conf.startAttempt()
+	p, _ := conf.observe(pendingProposal{checkName: "wss", status: false, errText: "still down", data: map[string]interface{}{"Peers": 0}}, true)
+	if p == nil {
+		t.Fatalf("expected third failure to confirm the change")
+	}
+	if p.errText != "still down" || p.data["Peers"] != 0 {
+		t.Fatalf("expected the latest observation to be proposed, got %#v", p)
+	}
+	attempts, ok := p.data["ConfirmAttempts"].([]map[string]interface{})
+	if !ok || len(attempts) != 3 {
+		t.Fatalf("expected three recorded attempts, got %#v", p.data["ConfirmAttempts"])
+	}
+	if conf.needsRetry() {
+		t.Fatalf("expected no retry once confirmed")
+	}
+}
+
+func TestConfirmationDropsChangeThatRecovers(t *testing.T) {
+	conf := newConfirmation(&CheckItem{Check: cfg.Check{ExtraOptions: map[string]interface{}{"ConfirmFailures": 3}}})
+
+	conf.startAttempt()
+	conf.observe(pendingProposal{status: false}, true)
+	conf.startAttempt()
+	if p, _ := conf.observe(pendingProposal{status: true}, false); p != nil {
+		t.Fatalf("expected recovered status not to be proposed")
+	}
+	if conf.needsRetry() {
+		t.Fatalf("expected no retry after the change went away")
+	}
+}
+
+func TestConfirmationAbandonsFamilyOutOfAttempts(t *testing.T) {
+	conf := newConfirmation(&CheckItem{Check: cfg.Check{ExtraOptions: map[string]interface{}{"ConfirmFailures": 2}}})
+
+	conf.startAttempt()
+	conf.observe(pendingProposal{status: false, ipv6: true}, true)
+	conf.startAttempt()
+	if p, _ := conf.observe(pendingProposal{status: false, ipv6: true}, true); p == nil {
+		t.Fatalf("expected the IPv6 change to be confirmed")
+	}
+	conf.observe(pendingProposal{status: false}, true)
+
+	if conf.needsRetry() {
+		t.Fatalf("expected no retry after the last attempt")
+	}
+	if dropped := conf.abandon(); dropped != 1 {
+		t.Fatalf("expected the late IPv4 change to be dropped, got %d", dropped)
+	}
+}
+
+func TestUpdateResultLocalRecordsConfirmAttempts(t *testing.T) {
+	check := cfg.Check{Name: "confirm-local"}
+	conf := newConfirmation(&CheckItem{Check: cfg.Check{ExtraOptions: map[string]interface{}{"ConfirmFailures": 3}}})
+	key := resultKey("site", check.Name, "", "", "")
+	confirmSessions.Store(key, conf)
+	defer confirmSessions.Delete(key)
+
+	var got []CheckResult
+	remove := AddResultListener(func(r CheckResult) {
+		if r.CheckName == check.Name {
+			got = append(got, r)
+		}
+	})
+	defer remove()
+
+	conf.startAttempt()
+	UpdateSiteResultLocal(check, cfg.Member{}, false, "down", map[string]interface{}{"Peers": 0}, false)
+	if len(got) != 1 {
+		t.Fatalf("expected one local result, got %d", len(got))
+	}
+	attempts, ok := got[0].Data["ConfirmAttempts"].([]map[string]interface{})
+	if !ok || len(attempts) != 1 || got[0].Data["Peers"] != 0 {
+		t.Fatalf("expected the attempt in the local result data, got %#v", got[0].Data)
+	}
+}
+
+func TestConfirmationDisabledByDefault(t *testing.T) {
+	if conf := newConfirmation(&CheckItem{Check: cfg.Check{}}); conf != nil {
+		t.Fatalf("expected confirmation to be disabled without options")
+	}
+}
+
+func TestRunConfirmedCheckRetriesUntilConfirmed(t *testing.T) {
+	item := &CheckItem{
+		Type:  "site",
+		Check: cfg.Check{Name: "ping", ExtraOptions: map[string]interface{}{"ConfirmFailures": 3, "RetryDelay": 0}},
+	}
+	key := resultKey(item.Type, item.Check.Name, item.Member.Details.Name, item.Domain, item.Endpoint)
+
+	runs := 0
+	var proposed *pendingProposal
+	runConfirmedCheck(context.Background(), item, func() {
+		runs++
+		v, ok := confirmSessions.Load(key)
+		if !ok {
+			t.Fatalf("expected a confirmation session during the run")
+		}
+		if p, _ := v.(*confirmation).observe(pendingProposal{status: false}, true); p != nil {
+			proposed = p
+		}
+	})
+
+	if runs != 3 {
+		t.Fatalf("expected 3 runs, got %d", runs)
+	}
+	if proposed == nil {
+		t.Fatalf("expected the change to be confirmed")
+	}
+	if _, ok := confirmSessions.Load(key); ok {
+		t.Fatalf("expected the session to be removed after the run")
+	}
+}
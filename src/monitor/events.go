@@ -0,0 +1,104 @@
+package monitor
+
+import (
+	"sync"
+	"time"
+)
+
+// StatusEvent is a status change seen by this monitor. A "transition" is a
+// local result that differs from the previous local result for the same
+// target and IP family; a "proposal" is a status change sent to NATS.
+type StatusEvent struct {
+	Kind      string // "transition" or "proposal"
+	Type      string // "site", "domain", "endpoint"
+	CheckName string
+	Member    string
+	Service   string // empty for site checks and proposals
+	Domain    string
+	Endpoint  string
+	IsIPv6    bool
+	Status    bool
+	ErrorText string
+	Data      map[string]interface{}
+	Time      time.Time
+}
+
+const (
+	StatusEventTransition = "transition"
+	StatusEventProposal   = "proposal"
+)
+
+var (
+	statusListenersMu sync.RWMutex
+	statusListeners   = make(map[int]func(StatusEvent))
+	nextStatusID      int
+)
+
+// AddStatusListener registers fn to receive every StatusEvent. The returned
+// function removes the listener again. fn runs on the check worker, so it
+// should hand the event off rather than block.
+func AddStatusListener(fn func(StatusEvent)) func() {
+	statusListenersMu.Lock()
+	defer statusListenersMu.Unlock()
+
+	nextStatusID++
+	id := nextStatusID
+	statusListeners[id] = fn
+
+	return func() {
+		statusListenersMu.Lock()
+		defer statusListenersMu.Unlock()
+		delete(statusListeners, id)
+	}
+}
+
+func notifyStatusListeners(ev StatusEvent) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+
+	statusListenersMu.RLock()
+	defer statusListenersMu.RUnlock()
+	for _, fn := range statusListeners {
+		fn(ev)
+	}
+}
+
+// localStatuses holds the last local status per result key and IP family.
+var localStatuses sync.Map
+
+func init() {
+	AddResultListener(trackLocalTransitions)
+}
+
+func localStatusKey(checkType, checkName, member, domain, endpoint string, ipv6 bool) string {
+	return resultKey(checkType, checkName, member, domain, endpoint) + "|" + ipFamily(ipv6)
+}
+
+// trackLocalTransitions emits a transition when a result's status differs
+// from the previous one for the same target. The first result after startup
+// has nothing to compare with and is not reported.
+func trackLocalTransitions(r CheckResult) {
+	if r.Inconclusive {
+		return
+	}
+	prev, loaded := localStatuses.Swap(localStatusKey(r.Type, r.CheckName, r.Member, r.Domain, r.Endpoint, r.IsIPv6), r.Status)
+	if !loaded || prev.(bool) == r.Status {
+		return
+	}
+
+	notifyStatusListeners(StatusEvent{
+		Kind:      StatusEventTransition,
+		Type:      r.Type,
+		CheckName: r.CheckName,
+		Member:    r.Member,
+		Service:   r.Service,
+		Domain:    r.Domain,
+		Endpoint:  r.Endpoint,
+		IsIPv6:    r.IsIPv6,
+		Status:    r.Status,
+		ErrorText: r.ErrorText,
+		Data:      r.Data,
+		Time:      r.Checktime,
+	})
+}
@@ -0,0 +1,67 @@
+package monitor
+
+import (
+	"testing"
+
+	cfg "github.com/ibp-network/ibp-geodns-libs/config"
+)
+
+func TestTrackLocalTransitionsReportsChangesPerFamily(t *testing.T) {
+	var events []StatusEvent
+	remove := AddStatusListener(func(ev StatusEvent) { events = append(events, ev) })
+	defer remove()
+	localStatuses.Range(func(k, _ any) bool { localStatuses.Delete(k); return true })
+
+	base := CheckResult{Type: "endpoint", CheckName: "wss", Member: "events-test", Endpoint: "wss://rpc.example/polkadot"}
+	results := []struct {
+		ipv6   bool
+		status bool
+	}{
+		{false, true},  // first observation, nothing to compare
+		{true, false},  // first IPv6 observation
+		{false, true},  // unchanged
+		{false, false}, // IPv4 goes down
+		{true, true},   // IPv6 comes up
+	}
+	for _, r := range results {
+		res := base
+		res.IsIPv6 = r.ipv6
+		res.Status = r.status
+		trackLocalTransitions(res)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("expected 2 transitions, got %d: %#v", len(events), events)
+	}
+	if events[0].Kind != StatusEventTransition || events[0].IsIPv6 || events[0].Status {
+		t.Fatalf("unexpected first transition %#v", events[0])
+	}
+	if !events[1].IsIPv6 || !events[1].Status {
+		t.Fatalf("unexpected second transition %#v", events[1])
+	}
+}
+
+func TestInconclusiveResultsAreNotTransitions(t *testing.T) {
+	localStatuses.Range(func(k, _ any) bool { localStatuses.Delete(k); return true })
+	check := cfg.Check{Name: "inconclusive-test"}
+	member := cfg.Member{}
+	member.Details.Name = "alpha"
+
+	var got []CheckResult
+	remove := AddResultListener(func(r CheckResult) {
+		if r.CheckName == check.Name {
+			got = append(got, r)
+		}
+	})
+	defer remove()
+
+	UpdateEndpointResultInconclusive(check, member, cfg.Service{}, "wss://rpc.example.net/polkadot", "too few sources", nil, false)
+	if len(got) != 1 || !got[0].Inconclusive || got[0].Status || got[0].Data["Inconclusive"] != true {
+		t.Fatalf("expected one inconclusive result, got %#v", got)
+	}
+	seeded := false
+	localStatuses.Range(func(_, _ any) bool { seeded = true; return false })
+	if seeded {
+		t.Fatalf("expected an inconclusive result not to seed transition tracking")
+	}
+}
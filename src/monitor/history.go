@@ -0,0 +1,28 @@
+package monitor
+
+import (
+	"github.com/ibp-network/ibp-geodns-monitor/src/history"
+)
+
+func init() {
+	AddResultListener(recordHistory)
+}
+
+func recordHistory(r CheckResult) {
+	if r.Inconclusive {
+		return
+	}
+	history.Append(history.Record{
+		Time:      r.Checktime,
+		Type:      r.Type,
+		Check:     r.CheckName,
+		Member:    r.Member,
+		Service:   r.Service,
+		Domain:    r.Domain,
+		Endpoint:  r.Endpoint,
+		IsIPv6:    r.IsIPv6,
+		Status:    r.Status,
+		ErrorText: r.ErrorText,
+		LatencyMs: float64(r.Duration.Microseconds()) / 1000,
+	})
+}
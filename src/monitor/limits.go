@@ -0,0 +1,135 @@
+package monitor
+
+import (
+	log "github.com/ibp-network/ibp-geodns-libs/logging"
+)
+
+// Concurrency limits keep the pool from opening many connections to one
+// member at once. A due item that would exceed a limit is deferred: it leaves
+// the heap and waits in cm.deferred under the member or IP key that blocked
+// it. When a running item releases that key, only the items waiting on it are
+// put back to be claimed again.
+
+const (
+	limitMember = "member"
+	limitIP     = "ip"
+)
+
+// limitKeys returns the running-count keys item occupies: its member and
+// each member address it connects to.
+func limitKeys(item *CheckItem) (member string, ips []string) {
+	member = "member|" + item.Member.Details.Name
+	for _, ip := range []string{item.Member.Service.ServiceIPv4, item.Member.Service.ServiceIPv6} {
+		if ip != "" {
+			ips = append(ips, "ip|"+ip)
+		}
+	}
+	return member, ips
+}
+
+// limitReachedLocked names the limit that running item now would exceed and
+// the key that is full, or returns "" when it may run.
+func (cm *CheckManager) limitReachedLocked(item *CheckItem) (limit, key string) {
+	member, ips := limitKeys(item)
+	if cm.maxPerMember > 0 && cm.running[member] >= cm.maxPerMember {
+		return limitMember, member
+	}
+	if cm.maxPerIP > 0 {
+		for _, key := range ips {
+			if cm.running[key] >= cm.maxPerIP {
+				return limitIP, key
+			}
+		}
+	}
+	return "", ""
+}
+
+// acquireLocked counts item as running. A claimed item's deferral ends here.
+func (cm *CheckManager) acquireLocked(item *CheckItem) {
+	if cm.running == nil {
+		cm.running = make(map[string]int)
+	}
+	item.DeferredBy = ""
+	member, ips := limitKeys(item)
+	for _, key := range append(ips, member) {
+		cm.running[key]++
+	}
+}
+
+// releaseLocked stops counting item as running and requeues the items that
+// were waiting on any of its keys.
+func (cm *CheckManager) releaseLocked(item *CheckItem) {
+	member, ips := limitKeys(item)
+	for _, key := range append(ips, member) {
+		if n := cm.running[key]; n > 1 {
+			cm.running[key] = n - 1
+		} else {
+			delete(cm.running, key)
+		}
+		cm.requeueDeferredKeyLocked(key)
+	}
+}
+
+// deferLocked parks item until key is released. Deferrals are counted and
+// logged once per item until it is claimed, not each time it is re-deferred.
+func (cm *CheckManager) deferLocked(item *CheckItem, limit, key string) {
+	if cm.deferred == nil {
+		cm.deferred = make(map[string][]*CheckItem)
+	}
+	if item.DeferredBy == "" {
+		checkDeferrals.WithLabelValues(item.Check.Name, limit).Inc()
+		log.Log(log.Debug, "Deferred %s %s for %s: per-%s concurrency limit reached",
+			item.Type, item.Check.Name, item.Member.Details.Name, limit)
+	}
+	item.DeferredBy = key
+	cm.deferred[key] = append(cm.deferred[key], item)
+}
+
+func (cm *CheckManager) requeueDeferredKeyLocked(key string) {
+	for _, item := range cm.deferred[key] {
+		cm.checkQueue.Add(item)
+	}
+	delete(cm.deferred, key)
+}
+
+// requeueDeferredLocked moves every deferred item back into the queue.
+func (cm *CheckManager) requeueDeferredLocked() {
+	for key := range cm.deferred {
+		cm.requeueDeferredKeyLocked(key)
+	}
+}
+
+func (cm *CheckManager) isDeferredLocked(item *CheckItem) bool {
+	if item.DeferredBy == "" {
+		return false
+	}
+	for _, d := range cm.deferred[item.DeferredBy] {
+		if d == item {
+			return true
+		}
+	}
+	return false
+}
+
+// deferredCount returns how many due items are waiting on a concurrency limit.
+func (cm *CheckManager) deferredCount() int {
+	cm.claimMu.Lock()
+	defer cm.claimMu.Unlock()
+	n := 0
+	for _, items := range cm.deferred {
+		n += len(items)
+	}
+	return n
+}
+
+// Snapshot returns the queued items followed by the deferred ones, whose
+// DeferredBy names the member or IP limit holding them back.
+func (cm *CheckManager) Snapshot() []*CheckItem {
+	cm.claimMu.Lock()
+	defer cm.claimMu.Unlock()
+	out := cm.checkQueue.Snapshot()
+	for _, items := range cm.deferred {
+		out = append(out, items...)
+	}
+	return out
+}
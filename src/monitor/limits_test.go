@@ -0,0 +1,159 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	cfg "github.com/ibp-network/ibp-geodns-libs/config"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func limitTestItem(check, member, ip string) *CheckItem {
+	m := cfg.Member{}
+	m.Details.Name = member
+	m.Service.ServiceIPv4 = ip
+	return &CheckItem{
+		Type:         "endpoint",
+		Check:        cfg.Check{Name: check},
+		Member:       m,
+		Endpoint:     "wss://" + check + ".example.com",
+		LastExecuted: time.Now().Add(-time.Hour),
+		Generation:   1,
+	}
+}
+
+func TestClaimDefersItemsOverMemberLimit(t *testing.T) {
+	cm := newCheckManager(4, InitOptions{MaxConcurrentPerMember: 1}, realClock{})
+	first := limitTestItem("wss", "alpha", "192.0.2.1")
+	second := limitTestItem("rpc", "alpha", "192.0.2.1")
+	other := limitTestItem("wss", "beta", "192.0.2.2")
+	second.LastExecuted = first.LastExecuted.Add(time.Second)
+	other.LastExecuted = first.LastExecuted.Add(2 * time.Second)
+	for _, it := range []*CheckItem{first, second, other} {
+		cm.checkQueue.Add(it)
+	}
+
+	if got := cm.claimNextItem(); got != first {
+		t.Fatalf("expected the earliest item first, got %#v", got)
+	}
+	if got := cm.claimNextItem(); got != other {
+		t.Fatalf("expected alpha's second item to be skipped for beta, got %#v", got)
+	}
+	if got := cm.claimNextItem(); got != nil {
+		t.Fatalf("expected nothing claimable while alpha is busy, got %#v", got)
+	}
+	if n := cm.deferredCount(); n != 1 {
+		t.Fatalf("expected one deferred item, got %d", n)
+	}
+
+	cm.finishItem(first)
+	if n := cm.deferredCount(); n != 0 {
+		t.Fatalf("expected deferred items to be requeued when a slot frees, got %d", n)
+	}
+	if got := cm.claimNextItem(); got != second {
+		t.Fatalf("expected the deferred item once alpha is free, got %#v", got)
+	}
+}
+
+func TestClaimDefersItemsOverIPLimit(t *testing.T) {
+	cm := newCheckManager(4, InitOptions{MaxConcurrentPerIP: 1}, realClock{})
+	// Two members fronted by the same address.
+	first := limitTestItem("wss", "alpha", "192.0.2.1")
+	shared := limitTestItem("wss", "beta", "192.0.2.1")
+	shared.LastExecuted = first.LastExecuted.Add(time.Second)
+	cm.checkQueue.Add(first)
+	cm.checkQueue.Add(shared)
+
+	if got := cm.claimNextItem(); got != first {
+		t.Fatalf("expected the first item to be claimed, got %#v", got)
+	}
+	if got := cm.claimNextItem(); got != nil {
+		t.Fatalf("expected the shared IP to defer beta, got %#v", got)
+	}
+	cm.finishItem(first)
+	if got := cm.claimNextItem(); got != shared {
+		t.Fatalf("expected beta once the IP is free, got %#v", got)
+	}
+	cm.finishItem(shared)
+	if len(cm.running) != 0 {
+		t.Fatalf("expected running counts to drain, got %v", cm.running)
+	}
+}
+
+func TestApplyConfigRequeuesDeferredItems(t *testing.T) {
+	cm := newCheckManager(4, InitOptions{MaxConcurrentPerMember: 1}, realClock{})
+	cm.initializeChecks(reloadTestConfig(60, "alpha"))
+	busy := limitTestItem("rpc", "alpha", "192.0.2.9")
+	cm.claimMu.Lock()
+	cm.acquireLocked(busy)
+	cm.claimMu.Unlock()
+
+	if got := cm.claimNextItem(); got != nil {
+		t.Fatalf("expected alpha's ping to be deferred, got %#v", got)
+	}
+	if n := cm.deferredCount(); n != 1 {
+		t.Fatalf("expected one deferred item, got %d", n)
+	}
+
+	diff := cm.applyConfig(reloadTestConfig(60, "alpha"))
+	if diff.unchanged != 1 || cm.deferredCount() != 0 || cm.checkQueue.Count() != 1 {
+		t.Fatalf("expected the deferred item back in the queue and unchanged, got %+v, %d queued", diff, cm.checkQueue.Count())
+	}
+}
+
+// deferralsCounted reads ibp_monitor_check_deferrals_total for check and limit.
+func deferralsCounted(t *testing.T, check, limit string) float64 {
+	t.Helper()
+	var m dto.Metric
+	if err := checkDeferrals.WithLabelValues(check, limit).Write(&m); err != nil {
+		t.Fatalf("read deferrals: %v", err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+func TestReleaseRequeuesOnlyItemsWaitingOnThatKey(t *testing.T) {
+	cm := newCheckManager(4, InitOptions{MaxConcurrentPerMember: 1}, realClock{})
+	alphaBusy := limitTestItem("deferkey-busy", "alpha", "192.0.2.1")
+	betaBusy := limitTestItem("deferkey-busy", "beta", "192.0.2.2")
+	alphaWait := limitTestItem("deferkey", "alpha", "192.0.2.1")
+	betaWait := limitTestItem("deferkey", "beta", "192.0.2.2")
+	cm.checkQueue.Add(alphaBusy)
+	cm.checkQueue.Add(betaBusy)
+	cm.claimNextItem()
+	cm.claimNextItem()
+	cm.checkQueue.Add(alphaWait)
+	cm.checkQueue.Add(betaWait)
+	before := deferralsCounted(t, "deferkey", limitMember)
+
+	if got := cm.claimNextItem(); got != nil {
+		t.Fatalf("expected both members to be busy, got %#v", got)
+	}
+	if alphaWait.DeferredBy != "member|alpha" || betaWait.DeferredBy != "member|beta" {
+		t.Fatalf("expected each item deferred by its member, got %q and %q", alphaWait.DeferredBy, betaWait.DeferredBy)
+	}
+
+	cm.finishItem(alphaBusy)
+	if cm.checkQueue.Contains(betaWait) || !cm.checkQueue.Contains(alphaWait) {
+		t.Fatalf("expected only alpha's waiting item to be requeued")
+	}
+
+	// Snapshot still lists beta's item, with the limit holding it back.
+	var snapped *CheckItem
+	for _, it := range cm.Snapshot() {
+		if it == betaWait {
+			snapped = it
+		}
+	}
+	if snapped == nil || snapped.DeferredBy != "member|beta" {
+		t.Fatalf("expected the deferred item in the snapshot, got %#v", snapped)
+	}
+
+	// alpha's item is busy again; a re-deferral is not counted again.
+	cm.claimMu.Lock()
+	cm.acquireLocked(limitTestItem("deferkey-busy", "alpha", "192.0.2.1"))
+	cm.claimMu.Unlock()
+	cm.claimNextItem()
+	if got := deferralsCounted(t, "deferkey", limitMember) - before; got != 2 {
+		t.Fatalf("expected one deferral per item, got %v", got)
+	}
+}
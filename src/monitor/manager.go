@@ -1,6 +1,7 @@
 package monitor
 
 import (
+	"context"
 	"reflect"
 	"sync"
 	"sync/atomic"
@@ -11,50 +12,80 @@ import (
 )
 
 type CheckManager struct {
-	workers      []*Worker
-	checkQueue   *CheckQueue
-	numWorkers   int
-	separationMs int
-	shutdownCh   chan struct{}
-	wg           sync.WaitGroup
-	startOnce    sync.Once
-	stopOnce     sync.Once
-	claimMu      sync.Mutex
-	lastConfig   cfg.Config
-	generation   atomic.Int64
-	reloading    atomic.Bool
-	lastRuns     map[string]time.Time
-	lastRunsMu   sync.Mutex
-	activeWG     sync.WaitGroup
+	workers    []*Worker
+	checkQueue *CheckQueue
+	numWorkers int
+	shutdownCh chan struct{}
+	wg         sync.WaitGroup
+	startOnce  sync.Once
+	stopOnce   sync.Once
+	claimMu    sync.Mutex
+	lastConfig cfg.Config
+	items      map[string]*CheckItem // scheduled item per itemKey, guarded by claimMu
+	generation atomic.Int64
+	lastRuns   map[string]time.Time
+	lastRunsMu sync.Mutex
+	inFlight   atomic.Int64
+
+	// Scheduler state; see scheduler.go.
+	clock            clock
+	jobs             chan *CheckItem
+	idle             chan struct{}
+	wake             chan struct{}
+	dispatchInterval time.Duration
+	nextDispatch     time.Time
+	nodeID           string
+	jitterPercent    int
+
+	// State snapshot; see state.go. Empty statePath disables it.
+	statePath     string
+	stateMaxAge   time.Duration
+	stateInterval time.Duration
+	restoredMu    sync.Mutex
+	restored      map[string]time.Time // original Checktime of restored results not yet replaced
+	stopRestored  func()
+
+	// Concurrency limits; see limits.go. Guarded by claimMu.
+	maxPerMember int
+	maxPerIP     int
+	running      map[string]int
+	deferred     map[string][]*CheckItem // deferred items by the limit key blocking them
 }
 
 type Worker struct {
-	id         int
-	manager    *CheckManager
-	startDelay time.Duration
-	ticker     *time.Ticker
+	id      int
+	manager *CheckManager
 }
 
-func NewCheckManager() *CheckManager {
+func NewCheckManager(opts InitOptions) *CheckManager {
 	c := cfg.GetConfig()
 	numWorkers := c.Local.CheckWorkers.NumWorkers
 	if numWorkers <= 0 {
 		numWorkers = 10 // default
 	}
+	return newCheckManager(numWorkers, opts, realClock{})
+}
 
-	separationMs := c.Local.CheckWorkers.SeparationInterval
-	if separationMs <= 0 {
-		separationMs = 1000 // default 1 second
-	}
-
+func newCheckManager(numWorkers int, opts InitOptions, clk clock) *CheckManager {
 	cm := &CheckManager{
-		workers:      make([]*Worker, numWorkers),
-		checkQueue:   NewCheckQueue(),
-		numWorkers:   numWorkers,
-		separationMs: separationMs,
-		shutdownCh:   make(chan struct{}),
-		lastRuns:     make(map[string]time.Time),
-		activeWG:     sync.WaitGroup{},
+		workers:          make([]*Worker, numWorkers),
+		checkQueue:       NewCheckQueue(),
+		numWorkers:       numWorkers,
+		shutdownCh:       make(chan struct{}),
+		lastRuns:         make(map[string]time.Time),
+		clock:            clk,
+		jobs:             make(chan *CheckItem, numWorkers),
+		idle:             make(chan struct{}, numWorkers),
+		wake:             make(chan struct{}, 1),
+		dispatchInterval: rateInterval(opts.MaxChecksPerSecond),
+		maxPerMember:     max(opts.MaxConcurrentPerMember, 0),
+		maxPerIP:         max(opts.MaxConcurrentPerIP, 0),
+		nodeID:           opts.NodeID,
+		jitterPercent:    min(max(opts.JitterPercent, 0), 100),
+		statePath:        opts.StatePath,
+		stateMaxAge:      opts.StateMaxAge,
+		stateInterval:    opts.StateSaveInterval,
+		restored:         make(map[string]time.Time),
 	}
 	cm.generation.Store(1)
 	return cm
@@ -62,28 +93,37 @@ func NewCheckManager() *CheckManager {
 
 func (cm *CheckManager) Start() {
 	cm.startOnce.Do(func() {
-		log.Log(log.Info, "Starting CheckManager with %d workers, %dms separation",
-			cm.numWorkers, cm.separationMs)
+		rate := "unlimited"
+		if cm.dispatchInterval > 0 {
+			rate = cm.dispatchInterval.String() + " between dispatches"
+		}
+		log.Log(log.Info, "Starting CheckManager with %d workers, rate %s, per-member limit %d, per-IP limit %d (0 = none), jitter %d%%",
+			cm.numWorkers, rate, cm.maxPerMember, cm.maxPerIP, cm.jitterPercent)
+
+		var saved managerState
+		if cm.statePath != "" {
+			saved = cm.restoreState()
+		}
 
 		// Initialize all checks in the queue from a single config snapshot.
 		cm.lastConfig = cfg.GetConfig()
 		cm.initializeChecks(cm.lastConfig)
 
-		// Start workers with staggered delays
-		for i := 0; i < cm.numWorkers; i++ {
-			worker := &Worker{
-				id:         i,
-				manager:    cm,
-				startDelay: time.Duration(i*cm.separationMs) * time.Millisecond,
-			}
-			cm.workers[i] = worker
-			cm.wg.Add(1)
-			go worker.run()
+		if cm.statePath != "" {
+			cm.stopRestored = AddResultListener(cm.forgetRestored)
+			cm.restoreResults(saved)
 		}
 
+		cm.startScheduler()
+
 		// Start the queue maintenance routine
 		cm.wg.Add(1)
 		go cm.maintainQueue()
+
+		if cm.statePath != "" && cm.stateInterval > 0 {
+			cm.wg.Add(1)
+			go cm.persistState()
+		}
 	})
 }
 
@@ -92,6 +132,12 @@ func (cm *CheckManager) Stop() {
 		log.Log(log.Info, "Stopping CheckManager...")
 		close(cm.shutdownCh)
 		cm.wg.Wait()
+		if cm.statePath != "" {
+			cm.saveState()
+		}
+		if cm.stopRestored != nil {
+			cm.stopRestored()
+		}
 		log.Log(log.Info, "CheckManager stopped")
 	})
 }
@@ -101,44 +147,69 @@ func (cm *CheckManager) currentGeneration() int64 {
 }
 
 func (cm *CheckManager) initializeChecks(c cfg.Config) {
+	cm.claimMu.Lock()
+	defer cm.claimMu.Unlock()
+
+	now := cm.now()
+	cm.items = cm.buildCheckItems(c)
+	for _, item := range cm.items {
+		cm.applyLastExecuted(item)
+		cm.applyJitter(item, now)
+		cm.checkQueue.Add(item)
+	}
+
+	log.Log(log.Info, "Initialized %d checks in queue", cm.checkQueue.Count())
+
+	// Prune lastRuns to only current items
+	cm.pruneLastRuns()
+}
+
+// buildCheckItems expands c into one item per check target, keyed by itemKey
+// and stamped with the current generation.
+func (cm *CheckManager) buildCheckItems(c cfg.Config) map[string]*CheckItem {
+	items := make(map[string]*CheckItem)
 	for _, check := range c.Local.Checks {
 		if check.Enabled != 1 {
 			continue
 		}
 
+		var built []*CheckItem
 		switch check.CheckType {
 		case "site":
-			cm.initializeSiteChecks(c, check)
+			built = cm.siteCheckItems(c, check)
 		case "domain":
-			cm.initializeDomainChecks(c, check)
+			built = cm.domainCheckItems(c, check)
 		case "endpoint":
-			cm.initializeEndpointChecks(c, check)
+			built = cm.endpointCheckItems(c, check)
+		}
+		for _, item := range built {
+			key := itemKey(item)
+			if _, dup := items[key]; !dup {
+				items[key] = item
+			}
 		}
 	}
-
-	log.Log(log.Info, "Initialized %d checks in queue", cm.checkQueue.Count())
-
-	// Prune lastRuns to only current items
-	cm.pruneLastRuns()
+	return items
 }
 
-func (cm *CheckManager) initializeSiteChecks(c cfg.Config, check cfg.Check) {
+func (cm *CheckManager) siteCheckItems(c cfg.Config, check cfg.Check) []*CheckItem {
+	var items []*CheckItem
 	for _, member := range c.Members {
 		if member.Service.Active == 1 && !member.Override {
-			item := &CheckItem{
+			items = append(items, &CheckItem{
 				Type:            "site",
 				Check:           check,
 				Member:          member,
 				MinimumInterval: time.Duration(check.MinimumInterval) * time.Second,
 				Generation:      cm.currentGeneration(),
-			}
-			cm.applyLastExecuted(item)
-			cm.checkQueue.Add(item)
+			})
 		}
 	}
+	return items
 }
 
-func (cm *CheckManager) initializeDomainChecks(c cfg.Config, check cfg.Check) {
+func (cm *CheckManager) domainCheckItems(c cfg.Config, check cfg.Check) []*CheckItem {
+	var items []*CheckItem
 	for svcName, svc := range c.Services {
 		if !isCheckValidForServiceType(check.Name, "domain", svc.Configuration.ServiceType) {
 			continue
@@ -151,7 +222,7 @@ func (cm *CheckManager) initializeDomainChecks(c cfg.Config, check cfg.Check) {
 
 				domains := extractDomains(svc)
 				for domain := range domains {
-					item := &CheckItem{
+					items = append(items, &CheckItem{
 						Type:            "domain",
 						Check:           check,
 						Member:          mem,
@@ -159,16 +230,16 @@ func (cm *CheckManager) initializeDomainChecks(c cfg.Config, check cfg.Check) {
 						Domain:          domain,
 						MinimumInterval: time.Duration(check.MinimumInterval) * time.Second,
 						Generation:      cm.currentGeneration(),
-					}
-					cm.applyLastExecuted(item)
-					cm.checkQueue.Add(item)
+					})
 				}
 			}
 		}
 	}
+	return items
 }
 
-func (cm *CheckManager) initializeEndpointChecks(c cfg.Config, check cfg.Check) {
+func (cm *CheckManager) endpointCheckItems(c cfg.Config, check cfg.Check) []*CheckItem {
+	var items []*CheckItem
 	for svcName, svc := range c.Services {
 		if !isCheckValidForServiceType(check.Name, "endpoint", svc.Configuration.ServiceType) {
 			continue
@@ -181,7 +252,7 @@ func (cm *CheckManager) initializeEndpointChecks(c cfg.Config, check cfg.Check)
 
 				for _, prov := range svc.Providers {
 					for _, endpoint := range prov.RpcUrls {
-						item := &CheckItem{
+						items = append(items, &CheckItem{
 							Type:            "endpoint",
 							Check:           check,
 							Member:          mem,
@@ -190,14 +261,13 @@ func (cm *CheckManager) initializeEndpointChecks(c cfg.Config, check cfg.Check)
 							Domain:          parseUrlForDomain(endpoint),
 							MinimumInterval: time.Duration(check.MinimumInterval) * time.Second,
 							Generation:      cm.currentGeneration(),
-						}
-						cm.applyLastExecuted(item)
-						cm.checkQueue.Add(item)
+						})
 					}
 				}
 			}
 		}
 	}
+	return items
 }
 
 func (cm *CheckManager) maintainQueue() {
@@ -223,59 +293,80 @@ func (cm *CheckManager) updateChecksFromConfig() {
 		return // no change, skip reload
 	}
 
-	cm.claimMu.Lock()
-	cm.reloading.Store(true)
-	cm.claimMu.Unlock()
-
-	// Wait for in-flight and already-claimed checks to finish so we don't lose their last-run times.
-	cm.activeWG.Wait()
-
-	// Bump generation to invalidate in-flight/stale items
-	cm.generation.Add(1)
-
-	// Flush and rebuild the queue from the latest configuration
-	cm.checkQueue.Clear()
-	cm.initializeChecks(currentCfg)
-
+	diff := cm.applyConfig(currentCfg)
 	cm.lastConfig = currentCfg
-	cm.reloading.Store(false)
-}
 
-func (w *Worker) run() {
-	defer w.manager.wg.Done()
+	log.Log(log.Info, "Config reload (generation %d): %d added, %d removed, %d updated, %d unchanged; %d checks queued",
+		cm.currentGeneration(), diff.added, diff.removed, diff.updated, diff.unchanged, cm.checkQueue.Count())
+}
 
-	// Initial delay to stagger workers
-	time.Sleep(w.startDelay)
+type reloadDiff struct {
+	added, removed, updated, unchanged int
+}
 
-	// Calculate interval: numWorkers * separationMs
-	interval := time.Duration(w.manager.numWorkers*w.manager.separationMs) * time.Millisecond
-	w.ticker = time.NewTicker(interval)
-	defer w.ticker.Stop()
+// applyConfig brings the scheduled items in line with c, touching only the
+// items whose itemKey was added or removed or whose settings changed. Items
+// claimed by a worker keep running; finishItem requeues them, or their
+// replacement, when they complete.
+func (cm *CheckManager) applyConfig(c cfg.Config) reloadDiff {
+	cm.claimMu.Lock()
+	defer cm.claimMu.Unlock()
 
-	log.Log(log.Debug, "Worker %d started with %v interval after %v delay",
-		w.id, interval, w.startDelay)
+	gen := cm.generation.Add(1)
+	desired := cm.buildCheckItems(c)
 
-	// Execute first check immediately after delay
-	w.executeReadyChecks()
+	// Deferred items are neither queued nor running; put them back so the
+	// diff below sees them in the queue.
+	cm.requeueDeferredLocked()
 
-	for {
-		select {
-		case <-w.ticker.C:
-			w.executeReadyChecks()
-		case <-w.manager.shutdownCh:
-			return
+	now := cm.now()
+	var diff reloadDiff
+	for key, old := range cm.items {
+		if _, ok := desired[key]; !ok {
+			delete(cm.items, key)
+			cm.checkQueue.Remove(old)
+			diff.removed++
 		}
 	}
-}
 
-func (w *Worker) executeReadyChecks() {
-	item := w.manager.claimNextItem()
-	if item == nil {
-		return
+	for key, item := range desired {
+		old, ok := cm.items[key]
+		switch {
+		case !ok:
+			cm.applyLastExecuted(item)
+			cm.applyJitter(item, now)
+			cm.items[key] = item
+			cm.checkQueue.Add(item)
+			diff.added++
+		case itemSettingsChanged(old, item):
+			item.LastExecuted = old.LastExecuted
+			cm.applyJitter(item, now)
+			item.NotBefore = old.NotBefore
+			cm.items[key] = item
+			// A claimed item is swapped for its replacement in finishItem.
+			if cm.checkQueue.Remove(old) {
+				cm.checkQueue.Add(item)
+			}
+			diff.updated++
+		default:
+			diff.unchanged++
+		}
 	}
 
-	w.executeCheck(item)
-	w.manager.finishItem(item)
+	// Everything still queued belongs to the new configuration.
+	cm.checkQueue.SetGeneration(gen)
+	cm.pruneLastRuns()
+	cm.signal()
+	return diff
+}
+
+// itemSettingsChanged reports whether next differs from cur in anything the
+// check reads; the identity fields are already equal through itemKey.
+func itemSettingsChanged(cur, next *CheckItem) bool {
+	return cur.MinimumInterval != next.MinimumInterval ||
+		!reflect.DeepEqual(cur.Check, next.Check) ||
+		!reflect.DeepEqual(cur.Member, next.Member) ||
+		!reflect.DeepEqual(cur.Service, next.Service)
 }
 
 func (cm *CheckManager) applyLastExecuted(item *CheckItem) {
@@ -297,21 +388,14 @@ func (cm *CheckManager) recordLastRun(item *CheckItem) {
 	cm.lastRuns[itemKey(item)] = item.LastExecuted
 }
 
+// pruneLastRuns drops last-run times for items no longer scheduled. Callers
+// hold claimMu.
 func (cm *CheckManager) pruneLastRuns() {
 	cm.lastRunsMu.Lock()
 	defer cm.lastRunsMu.Unlock()
 
-	valid := make(map[string]struct{})
-	currentGeneration := cm.currentGeneration()
-	for _, it := range cm.checkQueue.Snapshot() {
-		if it == nil || it.Generation != currentGeneration {
-			continue
-		}
-		valid[itemKey(it)] = struct{}{}
-	}
-
 	for k := range cm.lastRuns {
-		if _, ok := valid[k]; !ok {
+		if _, ok := cm.items[k]; !ok {
 			delete(cm.lastRuns, k)
 		}
 	}
@@ -321,26 +405,47 @@ func (cm *CheckManager) claimNextItem() *CheckItem {
 	cm.claimMu.Lock()
 	defer cm.claimMu.Unlock()
 
-	if cm.reloading.Load() {
-		return nil
-	}
+	now := cm.now()
+	for {
+		item := cm.checkQueue.GetNextAt(cm.currentGeneration(), now)
+		if item == nil {
+			return nil
+		}
+		if limit, key := cm.limitReachedLocked(item); limit != "" {
+			cm.deferLocked(item, limit, key)
+			continue
+		}
 
-	item := cm.checkQueue.GetNext(cm.currentGeneration())
-	if item != nil {
-		cm.activeWG.Add(1)
+		cm.acquireLocked(item)
+		cm.inFlight.Add(1)
+		observeLateness(item, now)
+		return item
 	}
-	return item
 }
 
 func (cm *CheckManager) finishItem(item *CheckItem) {
-	item.LastExecuted = time.Now()
+	item.LastExecuted = cm.now()
 	cm.recordLastRun(item)
 
-	if !cm.reloading.Load() && item.Generation == cm.currentGeneration() {
+	cm.claimMu.Lock()
+	cm.releaseLocked(item)
+	cur, tracked := cm.items[itemKey(item)]
+	switch {
+	case item.Generation == cm.currentGeneration():
+		cm.checkQueue.Add(item)
+	case tracked && cur == item:
+		// Unchanged by a reload that ran while this item was claimed.
+		item.Generation = cm.currentGeneration()
 		cm.checkQueue.Add(item)
+	case tracked && !cm.checkQueue.Contains(cur) && !cm.isDeferredLocked(cur):
+		// Updated by a reload while claimed; schedule the replacement.
+		cur.LastExecuted = item.LastExecuted
+		cm.checkQueue.Add(cur)
 	}
+	cm.claimMu.Unlock()
+	cm.signal()
 
-	cm.activeWG.Done()
+	cm.inFlight.Add(-1)
 }
 
 func (w *Worker) executeCheck(item *CheckItem) {
@@ -351,18 +456,35 @@ func (w *Worker) executeCheck(item *CheckItem) {
 		}
 	}()
 
+	runCheckItem(context.Background(), item)
+}
+
+// runCheckItem runs the registered check for item, repeating it while a status
+// change awaits confirmation.
+func runCheckItem(parent context.Context, item *CheckItem) {
+	runConfirmedCheck(parent, item, func() { runCheckAttempt(parent, item) })
+}
+
+// runCheckAttempt runs the registered check for item once, bounded by its Timeout.
+func runCheckAttempt(parent context.Context, item *CheckItem) {
+	ctx, cancel := checkContext(parent, item.Check)
+	defer cancel()
+
+	runKey := markRunStart(item)
+	defer runMarks.Delete(runKey)
+
 	switch item.Type {
 	case "site":
 		if fn, ok := getSiteCheck(item.Check.Name); ok {
-			fn(item.Check, item.Member)
+			fn(ctx, item.Check, item.Member)
 		}
 	case "domain":
 		if fn, ok := getDomainCheck(item.Check.Name); ok {
-			fn(item.Check, item.Domain, item.Service, item.Member)
+			fn(ctx, item.Check, item.Domain, item.Service, item.Member)
 		}
 	case "endpoint":
 		if fn, ok := getEndpointCheck(item.Check.Name); ok {
-			fn(item.Check, item.Endpoint, item.Service, item.Member)
+			fn(ctx, item.Check, item.Endpoint, item.Service, item.Member)
 		}
 	}
 }
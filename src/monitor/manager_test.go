@@ -1,8 +1,11 @@
 package monitor
 
 import (
+	"context"
 	"testing"
 	"time"
+
+	cfg "github.com/ibp-network/ibp-geodns-libs/config"
 )
 
 func TestCheckQueueGetNextDropsStaleItems(t *testing.T) {
@@ -30,13 +33,12 @@ func TestCheckQueueGetNextDropsStaleItems(t *testing.T) {
 	}
 }
 
-func TestClaimNextItemReturnsNilWhileReloading(t *testing.T) {
+func TestClaimNextItemDropsItemsFromOlderGeneration(t *testing.T) {
 	manager := &CheckManager{
 		checkQueue: NewCheckQueue(),
 		lastRuns:   make(map[string]time.Time),
 	}
-	manager.generation.Store(1)
-	manager.reloading.Store(true)
+	manager.generation.Store(2)
 	manager.checkQueue.Add(&CheckItem{
 		Generation:      1,
 		LastExecuted:    time.Now().Add(-time.Minute),
@@ -44,27 +46,25 @@ func TestClaimNextItemReturnsNilWhileReloading(t *testing.T) {
 	})
 
 	if got := manager.claimNextItem(); got != nil {
-		t.Fatalf("expected no claim while reloading, got %#v", got)
+		t.Fatalf("expected no claim of an item from an older generation, got %#v", got)
 	}
-	if remaining := manager.checkQueue.Count(); remaining != 1 {
-		t.Fatalf("expected queued work to remain untouched, got %d items", remaining)
+	if remaining := manager.checkQueue.Count(); remaining != 0 {
+		t.Fatalf("expected the stale item to be dropped, got %d items", remaining)
 	}
 }
 
-func TestFinishItemSkipsRequeueDuringReload(t *testing.T) {
+func TestFinishItemDropsItemRemovedByReload(t *testing.T) {
 	manager := &CheckManager{
 		checkQueue: NewCheckQueue(),
 		lastRuns:   make(map[string]time.Time),
 	}
-	manager.generation.Store(1)
-	manager.reloading.Store(true)
+	manager.generation.Store(2)
 
 	item := &CheckItem{Generation: 1}
-	manager.activeWG.Add(1)
 	manager.finishItem(item)
 
 	if remaining := manager.checkQueue.Count(); remaining != 0 {
-		t.Fatalf("expected no requeue while reloading, got %d items", remaining)
+		t.Fatalf("expected no requeue of an item the reload removed, got %d items", remaining)
 	}
 	if _, ok := manager.lastRuns[itemKey(item)]; !ok {
 		t.Fatalf("expected last run to be recorded")
@@ -79,10 +79,211 @@ func TestFinishItemRequeuesCurrentGeneration(t *testing.T) {
 	manager.generation.Store(3)
 
 	item := &CheckItem{Generation: 3}
-	manager.activeWG.Add(1)
 	manager.finishItem(item)
 
 	if remaining := manager.checkQueue.Count(); remaining != 1 {
 		t.Fatalf("expected item to be requeued, got %d items", remaining)
 	}
 }
+
+func TestExecuteCheckBoundsSiteCheckByTimeout(t *testing.T) {
+	const name = "test-timeout"
+	var (
+		hasDeadline bool
+		remaining   time.Duration
+	)
+	RegisterSiteCheck(name, func(ctx context.Context, check cfg.Check, member cfg.Member) {
+		var deadline time.Time
+		deadline, hasDeadline = ctx.Deadline()
+		remaining = time.Until(deadline)
+	})
+	t.Cleanup(func() { delete(CheckRegistry.Site, name) })
+
+	w := &Worker{manager: &CheckManager{}}
+	w.executeCheck(&CheckItem{Type: "site", Check: cfg.Check{Name: name, Timeout: 30}})
+
+	if !hasDeadline {
+		t.Fatalf("expected check context to carry a deadline")
+	}
+	if remaining <= 29*time.Second || remaining > 30*time.Second {
+		t.Fatalf("expected deadline ~30s out, got %v", remaining)
+	}
+}
+
+func TestCheckFailureTextReportsTimeout(t *testing.T) {
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	check := cfg.Check{Name: "wss", Timeout: 90}
+	if got := checkFailureText(ctx, check, "Failed to connect"); got != "Check timed out after 90s" {
+		t.Fatalf("expected timeout reason, got %q", got)
+	}
+	if got := checkFailureText(context.Background(), check, "Failed to connect"); got != "Failed to connect" {
+		t.Fatalf("expected original error text, got %q", got)
+	}
+}
+
+func TestRunCheckItemMeasuresEachFamilySeparately(t *testing.T) {
+	const name = "test-durations"
+	clk := newFakeClock()
+	resultClock = clk
+	t.Cleanup(func() { resultClock = realClock{} })
+	RegisterSiteCheck(name, func(ctx context.Context, check cfg.Check, member cfg.Member) {
+		clk.Advance(20 * time.Millisecond)
+		UpdateSiteResultLocal(check, member, true, "", nil, false)
+		clk.Advance(5 * time.Millisecond)
+		UpdateSiteResultLocal(check, member, true, "", nil, true)
+	})
+	t.Cleanup(func() { delete(CheckRegistry.Site, name) })
+
+	var results []CheckResult
+	remove := AddResultListener(func(r CheckResult) {
+		if r.CheckName == name {
+			results = append(results, r)
+		}
+	})
+	defer remove()
+
+	runCheckItem(context.Background(), &CheckItem{Type: "site", Check: cfg.Check{Name: name}})
+
+	if len(results) != 2 {
+		t.Fatalf("expected two results, got %d", len(results))
+	}
+	if results[0].Duration != 20*time.Millisecond {
+		t.Fatalf("expected IPv4 duration to cover the first probe, got %v", results[0].Duration)
+	}
+	if results[1].Duration != 5*time.Millisecond {
+		t.Fatalf("expected IPv6 duration to exclude the IPv4 probe, got %v", results[1].Duration)
+	}
+}
+
+func TestClaimAndFinishTrackInFlight(t *testing.T) {
+	manager := &CheckManager{
+		checkQueue: NewCheckQueue(),
+		lastRuns:   make(map[string]time.Time),
+	}
+	manager.generation.Store(1)
+	manager.checkQueue.Add(&CheckItem{Generation: 1, LastExecuted: time.Now().Add(-time.Minute)})
+
+	item := manager.claimNextItem()
+	if item == nil {
+		t.Fatalf("expected an item to be claimed")
+	}
+	if got := manager.inFlight.Load(); got != 1 {
+		t.Fatalf("expected one in-flight item, got %d", got)
+	}
+	manager.finishItem(item)
+	if got := manager.inFlight.Load(); got != 0 {
+		t.Fatalf("expected no in-flight items after finish, got %d", got)
+	}
+}
+
+func reloadTestConfig(interval int, members ...string) cfg.Config {
+	c := cfg.Config{Members: make(map[string]cfg.Member)}
+	c.Local.Checks = []cfg.Check{{Name: "ping", CheckType: "site", Enabled: 1, MinimumInterval: interval}}
+	for _, name := range members {
+		m := cfg.Member{}
+		m.Details.Name = name
+		m.Service.Active = 1
+		m.Service.ServiceIPv4 = "192.0.2.1"
+		c.Members[name] = m
+	}
+	return c
+}
+
+func queuedByMember(cm *CheckManager) map[string]*CheckItem {
+	out := make(map[string]*CheckItem)
+	for _, it := range cm.Snapshot() {
+		out[it.Member.Details.Name] = it
+	}
+	return out
+}
+
+func TestApplyConfigOnlyTouchesChangedItems(t *testing.T) {
+	cm := &CheckManager{checkQueue: NewCheckQueue(), lastRuns: make(map[string]time.Time)}
+	cm.generation.Store(1)
+	cm.initializeChecks(reloadTestConfig(60, "alpha", "beta", "gamma"))
+
+	before := queuedByMember(cm)
+	ran := time.Now().Add(-10 * time.Second)
+	before["alpha"].LastExecuted = ran
+
+	next := reloadTestConfig(60, "alpha", "beta", "delta")
+	beta := next.Members["beta"]
+	beta.Service.ServiceIPv6 = "2001:db8::2" // part of itemKey: remove + add
+	next.Members["beta"] = beta
+	alpha := next.Members["alpha"]
+	alpha.Membership.Level = 5 // settings only: same key, updated item
+	next.Members["alpha"] = alpha
+
+	diff := cm.applyConfig(next)
+	if diff.added != 2 || diff.removed != 2 || diff.updated != 1 || diff.unchanged != 0 {
+		t.Fatalf("unexpected diff %+v", diff)
+	}
+
+	after := queuedByMember(cm)
+	if len(after) != 3 || after["gamma"] != nil || after["delta"] == nil {
+		t.Fatalf("unexpected queue after reload: %v", after)
+	}
+	if after["alpha"] == before["alpha"] || !after["alpha"].LastExecuted.Equal(ran) {
+		t.Fatalf("expected alpha to be replaced and keep its schedule")
+	}
+	if after["alpha"].Member.Membership.Level != 5 {
+		t.Fatalf("expected alpha to carry the new member settings")
+	}
+	for name, it := range after {
+		if it.Generation != cm.currentGeneration() {
+			t.Fatalf("expected %s stamped with generation %d, got %d", name, cm.currentGeneration(), it.Generation)
+		}
+	}
+
+	if diff := cm.applyConfig(next); diff.unchanged != 3 || diff.added+diff.removed+diff.updated != 0 {
+		t.Fatalf("expected identical config to leave every item unchanged, got %+v", diff)
+	}
+	if after2 := queuedByMember(cm); after2["alpha"] != after["alpha"] {
+		t.Fatalf("expected unchanged item to stay queued as is")
+	}
+}
+
+func TestApplyConfigKeepsClaimedItemsRunning(t *testing.T) {
+	cm := &CheckManager{checkQueue: NewCheckQueue(), lastRuns: make(map[string]time.Time)}
+	cm.generation.Store(1)
+	cm.initializeChecks(reloadTestConfig(60, "alpha", "beta", "gamma"))
+
+	claimed := make(map[string]*CheckItem)
+	for i := 0; i < 3; i++ {
+		it := cm.claimNextItem()
+		if it == nil {
+			t.Fatalf("expected a ready item")
+		}
+		claimed[it.Member.Details.Name] = it
+	}
+
+	// alpha unchanged, beta updated, gamma removed while all three run.
+	next := reloadTestConfig(60, "alpha", "beta")
+	beta := next.Members["beta"]
+	beta.Membership.Level = 5
+	next.Members["beta"] = beta
+	cm.applyConfig(next)
+
+	for _, it := range claimed {
+		cm.finishItem(it)
+	}
+
+	queued := queuedByMember(cm)
+	if len(queued) != 2 || queued["gamma"] != nil {
+		t.Fatalf("expected alpha and beta requeued and gamma dropped, got %v", queued)
+	}
+	if queued["alpha"] != claimed["alpha"] {
+		t.Fatalf("expected unchanged alpha to be requeued as is")
+	}
+	if queued["beta"] == claimed["beta"] || queued["beta"].Member.Membership.Level != 5 {
+		t.Fatalf("expected beta to be swapped for its updated item")
+	}
+	if queued["beta"].LastExecuted.IsZero() {
+		t.Fatalf("expected beta replacement to inherit the finished run time")
+	}
+	if got := cm.inFlight.Load(); got != 0 {
+		t.Fatalf("expected nothing in flight, got %d", got)
+	}
+}
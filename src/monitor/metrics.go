@@ -0,0 +1,100 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/ibp-network/ibp-geodns-monitor/src/metrics"
+)
+
+var (
+	checkDuration = metrics.NewHistogramVec("ibp_monitor_check_duration_seconds",
+		"Time taken to produce a check result.",
+		[]float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+		"check", "member", "family")
+	checkResults = metrics.NewCounterVec("ibp_monitor_check_results_total",
+		"Local check results by outcome.",
+		"check", "member", "family", "status")
+	proposalsSent = metrics.NewCounterVec("ibp_monitor_proposals_total",
+		"Status change proposals sent to NATS.",
+		"type", "check")
+	checkLateness = metrics.NewHistogramVec("ibp_monitor_check_lateness_seconds",
+		"How far past its scheduled run (LastExecuted+MinimumInterval, or its jittered first run) an item was claimed.",
+		[]float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 900},
+		"type", "check")
+	checkDeferrals = metrics.NewCounterVec("ibp_monitor_check_deferrals_total",
+		"Due checks held back by a per-member or per-IP concurrency limit.",
+		"check", "limit")
+	unconfirmedChanges = metrics.NewCounterVec("ibp_monitor_unconfirmed_changes_total",
+		"Status changes dropped before confirmation, because the status recovered or attempts ran out.",
+		"check", "reason")
+)
+
+func init() {
+	metrics.NewGaugeFunc("ibp_monitor_queue_depth", "Items waiting in the check queue.", func() float64 {
+		if cm := currentManager(); cm != nil {
+			return float64(cm.checkQueue.Count())
+		}
+		return 0
+	})
+	metrics.NewGaugeFunc("ibp_monitor_queue_deferred", "Due items waiting on a concurrency limit.", func() float64 {
+		if cm := currentManager(); cm != nil {
+			return float64(cm.deferredCount())
+		}
+		return 0
+	})
+	metrics.NewGaugeFunc("ibp_monitor_checks_in_flight", "Checks claimed by workers and not yet finished.", func() float64 {
+		if cm := currentManager(); cm != nil {
+			return float64(cm.inFlight.Load())
+		}
+		return 0
+	})
+	metrics.NewGaugeFunc("ibp_monitor_config_generation", "Generation number of the loaded check configuration.", func() float64 {
+		if cm := currentManager(); cm != nil {
+			return float64(cm.currentGeneration())
+		}
+		return 0
+	})
+
+	AddResultListener(observeResultMetrics)
+}
+
+func currentManager() *CheckManager {
+	managerMu.Lock()
+	defer managerMu.Unlock()
+	return manager
+}
+
+func ipFamily(isIPv6 bool) string {
+	if isIPv6 {
+		return "v6"
+	}
+	return "v4"
+}
+
+func observeResultMetrics(r CheckResult) {
+	status := "pass"
+	switch {
+	case r.Inconclusive:
+		status = "inconclusive"
+	case !r.Status:
+		status = "fail"
+	}
+	family := ipFamily(r.IsIPv6)
+	checkResults.WithLabelValues(r.CheckName, r.Member, family, status).Inc()
+	if r.Duration > 0 {
+		checkDuration.WithLabelValues(r.CheckName, r.Member, family).Observe(r.Duration.Seconds())
+	}
+}
+
+// observeLateness records how late item was claimed relative to its schedule.
+// Items that have never run are not counted.
+func observeLateness(item *CheckItem, now time.Time) {
+	if item.LastExecuted.IsZero() {
+		return
+	}
+	late := now.Sub(item.nextRun())
+	if late < 0 {
+		late = 0
+	}
+	checkLateness.WithLabelValues(item.Type, item.Check.Name).Observe(late.Seconds())
+}
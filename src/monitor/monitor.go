@@ -2,6 +2,8 @@ package monitor
 
 import (
 	"sync"
+	"sync/atomic"
+	"time"
 
 	log "github.com/ibp-network/ibp-geodns-libs/logging"
 )
@@ -9,14 +11,39 @@ import (
 var (
 	manager   *CheckManager
 	managerMu sync.Mutex
+
+	// standalone disables NATS proposals; local results are the official ones.
+	standalone atomic.Bool
 )
 
-func Init() {
+// InitOptions controls how the monitor publishes its results.
+type InitOptions struct {
+	Standalone bool
+	// MaxChecksPerSecond caps how fast checks are dispatched; 0 means no cap.
+	MaxChecksPerSecond float64
+	// MaxConcurrentPerMember and MaxConcurrentPerIP cap the checks running at
+	// once against one member or one member IP; 0 means no cap.
+	MaxConcurrentPerMember int
+	MaxConcurrentPerIP     int
+	// NodeID and JitterPercent derive each item's schedule offset, up to
+	// JitterPercent of its MinimumInterval; 0 disables jitter.
+	NodeID        string
+	JitterPercent int
+	// StatePath is where last-run times and local results are saved every
+	// StateSaveInterval and on shutdown, and restored from on start. Entries
+	// older than StateMaxAge are discarded. Empty disables persistence.
+	StatePath         string
+	StateMaxAge       time.Duration
+	StateSaveInterval time.Duration
+}
+
+func Init(opts InitOptions) {
 	log.Log(log.Debug, "Monitor Package initializing...")
+	standalone.Store(opts.Standalone)
 
 	managerMu.Lock()
 	current := manager
-	manager = NewCheckManager()
+	manager = NewCheckManager(opts)
 	next := manager
 	managerMu.Unlock()
 
@@ -0,0 +1,88 @@
+package monitor
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	cfg "github.com/ibp-network/ibp-geodns-libs/config"
+)
+
+type timedObservation[T any] struct {
+	Value    T
+	Observed time.Time
+}
+
+// observationBoard keeps the latest value reported by every member endpoint
+// of a network, so each check run can be compared with the rest of the network.
+// It holds only this monitor's own observations since it started, so checks
+// require observationMinSources members before trusting a comparison.
+type observationBoard[T any] struct {
+	mu       sync.Mutex
+	networks map[string]map[string]timedObservation[T]
+}
+
+func newObservationBoard[T any]() *observationBoard[T] {
+	return &observationBoard[T]{networks: make(map[string]map[string]timedObservation[T])}
+}
+
+// record stores value for source, drops observations of network older than
+// window relative to observed, and returns the remaining values, including
+// value, by source.
+func (b *observationBoard[T]) record(network, source string, value T, observed time.Time, window time.Duration) map[string]T {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sources, ok := b.networks[network]
+	if !ok {
+		sources = make(map[string]timedObservation[T])
+		b.networks[network] = sources
+	}
+	sources[source] = timedObservation[T]{Value: value, Observed: observed}
+
+	values := make(map[string]T, len(sources))
+	for key, o := range sources {
+		if observed.Sub(o.Observed) > window {
+			delete(sources, key)
+			continue
+		}
+		values[key] = o.Value
+	}
+	return values
+}
+
+// observationMembers counts the distinct members among sources keyed by
+// observationScope, so one member's endpoints and IP families count once.
+func observationMembers[T any](sources map[string]T) int {
+	members := make(map[string]struct{}, len(sources))
+	for source := range sources {
+		member, _, _ := strings.Cut(source, "|")
+		members[member] = struct{}{}
+	}
+	return len(members)
+}
+
+// observationMinSources is how many members must have been observed before a
+// comparison with the network is trusted: MinSources, default 2. With fewer,
+// a member would mostly be compared with itself.
+func observationMinSources(check cfg.Check) int {
+	return max(getIntOption(check.ExtraOptions, "MinSources", 2), 1)
+}
+
+// observationWindow is how long another endpoint's observation still
+// describes the network: ObservationWindow seconds, three check intervals by
+// default, or 15 minutes when neither is set.
+func observationWindow(check cfg.Check) time.Duration {
+	window := time.Duration(getIntOption(check.ExtraOptions, "ObservationWindow", 3*check.MinimumInterval)) * time.Second
+	if window <= 0 {
+		window = 15 * time.Minute
+	}
+	return window
+}
+
+// observationScope returns the board keys of one check run: the network the
+// service belongs to and the member endpoint and IP that was observed.
+func observationScope(service cfg.Service, member cfg.Member, target CheckTarget, ip string) (string, string) {
+	return service.Configuration.ServiceType + "|" + service.Configuration.NetworkName,
+		member.Details.Name + "|" + target.URL + "|" + ip
+}
@@ -0,0 +1,52 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	cfg "github.com/ibp-network/ibp-geodns-libs/config"
+)
+
+func TestObservationBoardPrunesStaleAndSeparatesNetworks(t *testing.T) {
+	board := newObservationBoard[int64]()
+	now := time.Now()
+
+	board.record("RPC|Polkadot", "stale", 5000, now.Add(-time.Hour), time.Hour)
+	board.record("RPC|Kusama", "other-network", 9000, now, time.Minute)
+	board.record("RPC|Polkadot", "a", 1000, now, time.Minute)
+
+	values := board.record("RPC|Polkadot", "b", 990, now, time.Minute)
+	if len(values) != 2 {
+		t.Fatalf("expected the two fresh Polkadot observations, got %v", values)
+	}
+	if _, ok := board.networks["RPC|Polkadot"]["stale"]; ok {
+		t.Fatalf("expected stale observation to be pruned")
+	}
+}
+
+func TestObservationMembersCountsEachMemberOnce(t *testing.T) {
+	sources := map[string]int64{
+		"alpha|wss://rpc.example.net/polkadot|192.0.2.10":   1,
+		"alpha|wss://rpc.example.net/polkadot|2001:db8::10": 1,
+		"beta|wss://rpc.example.org/polkadot|192.0.2.20":    1,
+	}
+	if got := observationMembers(sources); got != 2 {
+		t.Fatalf("expected 2 members, got %d", got)
+	}
+	if got := observationMinSources(cfg.Check{}); got != 2 {
+		t.Fatalf("expected MinSources to default to 2, got %d", got)
+	}
+}
+
+func TestObservationWindowDefaults(t *testing.T) {
+	if got := observationWindow(cfg.Check{MinimumInterval: 60}); got != 3*time.Minute {
+		t.Fatalf("expected three check intervals, got %s", got)
+	}
+	if got := observationWindow(cfg.Check{}); got != 15*time.Minute {
+		t.Fatalf("expected 15 minute fallback, got %s", got)
+	}
+	check := cfg.Check{MinimumInterval: 60, ExtraOptions: map[string]interface{}{"ObservationWindow": 30}}
+	if got := observationWindow(check); got != 30*time.Second {
+		t.Fatalf("expected ObservationWindow option, got %s", got)
+	}
+}
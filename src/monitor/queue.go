@@ -17,10 +17,24 @@ type CheckItem struct {
 	Endpoint        string
 	LastExecuted    time.Time
 	MinimumInterval time.Duration
+	Jitter          time.Duration // per-node offset of the first run, which later runs keep as their phase
+	NotBefore       time.Time     // earliest first run after being scheduled
+	DeferredBy      string        // limit key ("member|<name>" or "ip|<addr>") holding the item back; empty once claimed
 	Generation      int64
 	index           int // Used by heap
 }
 
+// nextRun returns when item is due: one interval after its last run, and
+// never before NotBefore. The jitter is only applied through NotBefore, so the
+// interval between runs stays MinimumInterval.
+func (it *CheckItem) nextRun() time.Time {
+	next := it.LastExecuted.Add(it.MinimumInterval)
+	if next.Before(it.NotBefore) {
+		return it.NotBefore
+	}
+	return next
+}
+
 type CheckQueue struct {
 	mu    sync.Mutex
 	items []*CheckItem
@@ -42,6 +56,37 @@ func (cq *CheckQueue) Clear() {
 	heap.Init(cq)
 }
 
+// Remove takes item out of the queue, reporting whether it was queued.
+func (cq *CheckQueue) Remove(item *CheckItem) bool {
+	cq.mu.Lock()
+	defer cq.mu.Unlock()
+	if !cq.containsLocked(item) {
+		return false
+	}
+	heap.Remove(cq, item.index)
+	return true
+}
+
+// Contains reports whether item is currently queued.
+func (cq *CheckQueue) Contains(item *CheckItem) bool {
+	cq.mu.Lock()
+	defer cq.mu.Unlock()
+	return cq.containsLocked(item)
+}
+
+func (cq *CheckQueue) containsLocked(item *CheckItem) bool {
+	return item.index >= 0 && item.index < len(cq.items) && cq.items[item.index] == item
+}
+
+// SetGeneration stamps every queued item with generation.
+func (cq *CheckQueue) SetGeneration(generation int64) {
+	cq.mu.Lock()
+	defer cq.mu.Unlock()
+	for _, item := range cq.items {
+		item.Generation = generation
+	}
+}
+
 // Count returns the number of queued items.
 func (cq *CheckQueue) Count() int {
 	cq.mu.Lock()
@@ -64,9 +109,7 @@ func (cq *CheckQueue) Len() int { return len(cq.items) }
 
 func (cq *CheckQueue) Less(i, j int) bool {
 	// Earlier next run time has higher priority; no dependency on current time
-	iNext := cq.items[i].LastExecuted.Add(cq.items[i].MinimumInterval)
-	jNext := cq.items[j].LastExecuted.Add(cq.items[j].MinimumInterval)
-	return iNext.Before(jNext)
+	return cq.items[i].nextRun().Before(cq.items[j].nextRun())
 }
 
 func (cq *CheckQueue) Swap(i, j int) {
@@ -100,27 +143,42 @@ func (cq *CheckQueue) Add(item *CheckItem) {
 }
 
 func (cq *CheckQueue) GetNext(currentGeneration int64) *CheckItem {
+	return cq.GetNextAt(currentGeneration, time.Now())
+}
+
+// GetNextAt pops the earliest item if it is due at now.
+func (cq *CheckQueue) GetNextAt(currentGeneration int64, now time.Time) *CheckItem {
 	cq.mu.Lock()
 	defer cq.mu.Unlock()
 
-	if cq.Len() == 0 {
+	item := cq.peekLocked(currentGeneration)
+	// If the earliest item is not ready, none are
+	if item == nil || now.Before(item.nextRun()) {
 		return nil
 	}
+	return heap.Pop(cq).(*CheckItem)
+}
+
+// NextDue returns when the earliest queued item becomes due.
+func (cq *CheckQueue) NextDue(currentGeneration int64) (time.Time, bool) {
+	cq.mu.Lock()
+	defer cq.mu.Unlock()
+
+	item := cq.peekLocked(currentGeneration)
+	if item == nil {
+		return time.Time{}, false
+	}
+	return item.nextRun(), true
+}
 
-	now := time.Now()
+// peekLocked returns the earliest item, discarding stale generations on the way.
+func (cq *CheckQueue) peekLocked(currentGeneration int64) *CheckItem {
 	for cq.Len() > 0 {
 		item := cq.items[0]
-		// Discard stale generations
-		if item.Generation != currentGeneration {
-			heap.Pop(cq)
-			continue
-		}
-		// If the earliest item is not ready, none are
-		nextRun := item.LastExecuted.Add(item.MinimumInterval)
-		if now.Before(nextRun) {
-			return nil
+		if item.Generation == currentGeneration {
+			return item
 		}
-		return heap.Pop(cq).(*CheckItem)
+		heap.Pop(cq)
 	}
 	return nil
 }
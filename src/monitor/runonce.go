@@ -0,0 +1,206 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	cfg "github.com/ibp-network/ibp-geodns-libs/config"
+)
+
+// RunOnceOptions selects a single check execution for RunCheckOnce.
+type RunOnceOptions struct {
+	Check    string
+	Member   string
+	Endpoint string // optional; limits domain/endpoint checks to this URL's host or URL
+	IPv6     bool   // run against ServiceIPv6 instead of ServiceIPv4
+}
+
+// RunOnceItem is one executed queue item and the results it recorded.
+type RunOnceItem struct {
+	Type       string
+	Domain     string `json:",omitempty"`
+	Endpoint   string `json:",omitempty"`
+	Status     bool
+	StartedAt  time.Time
+	DurationMs int64
+	Results    []RunOnceResult
+}
+
+// RunOnceResult is a recorded result plus its offset from the item start.
+type RunOnceResult struct {
+	CheckResult
+	ElapsedMs int64
+}
+
+// RunOnceReport is the outcome of RunCheckOnce.
+type RunOnceReport struct {
+	Check      string
+	Member     string
+	IPv6       bool
+	Status     bool
+	DurationMs int64
+	Items      []RunOnceItem
+}
+
+// RunCheckOnce runs the named check against one member using the current
+// config, without the scheduler and without proposing to NATS. It builds the
+// same items the queue would, so endpoint and domain selection match a daemon run.
+func RunCheckOnce(ctx context.Context, c cfg.Config, opts RunOnceOptions) (RunOnceReport, error) {
+	check, err := resolveRunOnceCheck(c, opts.Check)
+	if err != nil {
+		return RunOnceReport{}, err
+	}
+
+	member, ok := findMember(c, opts.Member)
+	if !ok {
+		return RunOnceReport{}, fmt.Errorf("member %q not found in config", opts.Member)
+	}
+	if opts.IPv6 {
+		if member.Service.ServiceIPv6 == "" {
+			return RunOnceReport{}, fmt.Errorf("member %q has no ServiceIPv6 configured", opts.Member)
+		}
+		member.Service.ServiceIPv4 = ""
+	} else {
+		if member.Service.ServiceIPv4 == "" {
+			return RunOnceReport{}, fmt.Errorf("member %q has no ServiceIPv4 configured", opts.Member)
+		}
+		member.Service.ServiceIPv6 = ""
+	}
+
+	// Debug runs target the member even if it is inactive or under override.
+	member.Service.Active = 1
+	member.Override = false
+
+	// A one-off debug run must never start a consensus round.
+	prevStandalone := standalone.Swap(true)
+	defer standalone.Store(prevStandalone)
+
+	scoped := c
+	scoped.Local.Checks = []cfg.Check{check}
+	scoped.Members = map[string]cfg.Member{member.Details.Name: member}
+
+	cm := &CheckManager{
+		checkQueue: NewCheckQueue(),
+		lastRuns:   make(map[string]time.Time),
+	}
+	cm.generation.Store(1)
+	cm.initializeChecks(scoped)
+
+	items := make([]*CheckItem, 0)
+	for _, it := range cm.Snapshot() {
+		if opts.Endpoint != "" && !runOnceMatchesEndpoint(it, opts.Endpoint) {
+			continue
+		}
+		items = append(items, it)
+	}
+	if len(items) == 0 {
+		return RunOnceReport{}, fmt.Errorf("no %s items to run for member %q", check.Name, opts.Member)
+	}
+
+	report := RunOnceReport{
+		Check:  check.Name,
+		Member: member.Details.Name,
+		IPv6:   opts.IPv6,
+		Status: true,
+	}
+	started := time.Now()
+
+	for _, it := range items {
+		item := RunOnceItem{
+			Type:      it.Type,
+			Domain:    it.Domain,
+			Endpoint:  it.Endpoint,
+			Status:    true,
+			StartedAt: time.Now(),
+		}
+
+		remove := AddResultListener(func(r CheckResult) {
+			if r.CheckName != check.Name || r.Member != member.Details.Name {
+				return
+			}
+			item.Results = append(item.Results, RunOnceResult{
+				CheckResult: r,
+				ElapsedMs:   r.Checktime.Sub(item.StartedAt).Milliseconds(),
+			})
+			if !r.Status && !r.Inconclusive {
+				item.Status = false
+			}
+		})
+		runCheckItem(ctx, it)
+		remove()
+
+		item.DurationMs = time.Since(item.StartedAt).Milliseconds()
+		if len(item.Results) == 0 {
+			item.Status = false
+		}
+		if !item.Status {
+			report.Status = false
+		}
+		report.Items = append(report.Items, item)
+	}
+
+	report.DurationMs = time.Since(started).Milliseconds()
+	return report, nil
+}
+
+func resolveRunOnceCheck(c cfg.Config, name string) (cfg.Check, error) {
+	for _, check := range c.Local.Checks {
+		if check.Name == name {
+			check.Enabled = 1
+			return check, nil
+		}
+	}
+
+	// Allow registered checks that are absent from the config, with default options.
+	check := cfg.Check{Name: name, Enabled: 1}
+	switch {
+	case hasSiteCheck(name):
+		check.CheckType = "site"
+	case hasDomainCheck(name):
+		check.CheckType = "domain"
+	case hasEndpointCheck(name):
+		check.CheckType = "endpoint"
+	default:
+		return cfg.Check{}, fmt.Errorf("unknown check %q", name)
+	}
+	return check, nil
+}
+
+func hasSiteCheck(name string) bool {
+	_, ok := getSiteCheck(name)
+	return ok
+}
+
+func hasDomainCheck(name string) bool {
+	_, ok := getDomainCheck(name)
+	return ok
+}
+
+func hasEndpointCheck(name string) bool {
+	_, ok := getEndpointCheck(name)
+	return ok
+}
+
+func findMember(c cfg.Config, name string) (cfg.Member, bool) {
+	if m, ok := c.Members[name]; ok {
+		return m, true
+	}
+	for _, m := range c.Members {
+		if m.Details.Name == name {
+			return m, true
+		}
+	}
+	return cfg.Member{}, false
+}
+
+func runOnceMatchesEndpoint(it *CheckItem, endpoint string) bool {
+	switch it.Type {
+	case "endpoint":
+		return it.Endpoint == endpoint
+	case "domain":
+		return it.Domain == parseUrlForDomain(endpoint)
+	default:
+		return true
+	}
+}
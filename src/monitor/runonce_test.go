@@ -0,0 +1,58 @@
+package monitor
+
+import (
+	"context"
+	"testing"
+
+	cfg "github.com/ibp-network/ibp-geodns-libs/config"
+)
+
+func TestRunCheckOnceReportsFailureWithoutProposing(t *testing.T) {
+	const name = "test-runonce"
+	var sawStandalone bool
+	RegisterSiteCheck(name, func(ctx context.Context, check cfg.Check, member cfg.Member) {
+		sawStandalone = standalone.Load()
+		if member.Service.ServiceIPv6 != "" {
+			t.Errorf("expected IPv6 to be cleared for an IPv4 run")
+		}
+		UpdateSiteResultLocal(check, member, false, "unreachable", map[string]interface{}{"AvgRtt": 12}, false)
+	})
+	t.Cleanup(func() { delete(CheckRegistry.Site, name) })
+
+	member := cfg.Member{}
+	member.Details.Name = "alpha"
+	member.Service.ServiceIPv4 = "192.0.2.1"
+	member.Service.ServiceIPv6 = "2001:db8::1"
+
+	c := cfg.Config{Members: map[string]cfg.Member{"alpha": member}}
+	report, err := RunCheckOnce(context.Background(), c, RunOnceOptions{Check: name, Member: "alpha"})
+	if err != nil {
+		t.Fatalf("RunCheckOnce returned error: %v", err)
+	}
+
+	if !sawStandalone {
+		t.Fatalf("expected proposals to be disabled during the run")
+	}
+	if standalone.Load() {
+		t.Fatalf("expected standalone flag to be restored after the run")
+	}
+	if report.Status {
+		t.Fatalf("expected failing report")
+	}
+	if len(report.Items) != 1 || len(report.Items[0].Results) != 1 {
+		t.Fatalf("expected a single item with one result, got %#v", report.Items)
+	}
+	if got := report.Items[0].Results[0].ErrorText; got != "unreachable" {
+		t.Fatalf("expected recorded error text, got %q", got)
+	}
+}
+
+func TestRunCheckOnceRejectsUnknownCheckAndMember(t *testing.T) {
+	c := cfg.Config{Members: map[string]cfg.Member{}}
+	if _, err := RunCheckOnce(context.Background(), c, RunOnceOptions{Check: "no-such-check", Member: "alpha"}); err == nil {
+		t.Fatalf("expected unknown check to fail")
+	}
+	if _, err := RunCheckOnce(context.Background(), c, RunOnceOptions{Check: "ping", Member: "alpha"}); err == nil {
+		t.Fatalf("expected unknown member to fail")
+	}
+}
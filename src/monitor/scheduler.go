@@ -0,0 +1,153 @@
+package monitor
+
+import (
+	"hash/fnv"
+	"time"
+
+	log "github.com/ibp-network/ibp-geodns-libs/logging"
+)
+
+// clock is the time source of the scheduler, replaced in tests.
+type clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (cm *CheckManager) now() time.Time {
+	if cm.clock == nil {
+		return time.Now()
+	}
+	return cm.clock.Now()
+}
+
+// signal wakes the dispatcher after the queue changed.
+func (cm *CheckManager) signal() {
+	if cm.wake == nil {
+		return
+	}
+	select {
+	case cm.wake <- struct{}{}:
+	default:
+	}
+}
+
+// startScheduler starts the worker pool and the dispatcher feeding it.
+func (cm *CheckManager) startScheduler() {
+	for i := 0; i < cm.numWorkers; i++ {
+		worker := &Worker{id: i, manager: cm}
+		cm.workers[i] = worker
+		cm.idle <- struct{}{}
+		cm.wg.Add(1)
+		go worker.run()
+	}
+
+	cm.wg.Add(1)
+	go cm.dispatch()
+}
+
+// dispatch hands due items to idle workers. It sleeps until the earliest
+// nextRun in the queue, a queue change, or the next slot
+// allowed by the global rate, whichever applies.
+func (cm *CheckManager) dispatch() {
+	defer cm.wg.Done()
+
+	for {
+		select {
+		case <-cm.idle:
+		case <-cm.shutdownCh:
+			return
+		}
+
+		item := cm.waitForDueItem()
+		if item == nil {
+			return
+		}
+		// jobs holds one slot per worker, so this never blocks.
+		cm.jobs <- item
+	}
+}
+
+// waitForDueItem claims the next due item, or returns nil on shutdown.
+func (cm *CheckManager) waitForDueItem() *CheckItem {
+	for {
+		now := cm.now()
+		if wait := cm.nextDispatch.Sub(now); wait > 0 {
+			select {
+			case <-cm.clock.After(wait):
+				continue
+			case <-cm.shutdownCh:
+				return nil
+			}
+		}
+
+		if item := cm.claimNextItem(); item != nil {
+			if cm.dispatchInterval > 0 {
+				cm.nextDispatch = now.Add(cm.dispatchInterval)
+			}
+			return item
+		}
+
+		var timer <-chan time.Time
+		if due, ok := cm.checkQueue.NextDue(cm.currentGeneration()); ok {
+			timer = cm.clock.After(max(due.Sub(now), 0))
+		}
+		select {
+		case <-timer:
+		case <-cm.wake:
+		case <-cm.shutdownCh:
+			return nil
+		}
+	}
+}
+
+func (w *Worker) run() {
+	defer w.manager.wg.Done()
+
+	log.Log(log.Debug, "Worker %d started", w.id)
+	for {
+		select {
+		case item := <-w.manager.jobs:
+			w.executeCheck(item)
+			w.manager.finishItem(item)
+			w.manager.idle <- struct{}{}
+		case <-w.manager.shutdownCh:
+			return
+		}
+	}
+}
+
+// rateInterval converts a checks-per-second limit to the spacing between
+// dispatches; zero means no limit.
+func rateInterval(perSecond float64) time.Duration {
+	if perSecond <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / perSecond)
+}
+
+// scheduleJitter returns a stable offset in [0, percent% of interval) for the
+// item identified by key on node nodeID. Monitors started together therefore
+// spread their runs of the same item instead of probing in lockstep, and one
+// monitor spreads its items after a restart.
+func scheduleJitter(nodeID, key string, interval time.Duration, percent int) time.Duration {
+	if percent <= 0 || interval <= 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write([]byte(nodeID))
+	h.Write([]byte{'|'})
+	h.Write([]byte(key))
+	frac := float64(h.Sum64()>>11) / (1 << 53)
+	return time.Duration(frac * float64(interval) * float64(min(percent, 100)) / 100)
+}
+
+// applyJitter sets item's jitter and holds its first run back by it.
+func (cm *CheckManager) applyJitter(item *CheckItem, now time.Time) {
+	item.Jitter = scheduleJitter(cm.nodeID, itemKey(item), item.MinimumInterval, cm.jitterPercent)
+	item.NotBefore = now.Add(item.Jitter)
+}
@@ -0,0 +1,296 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	cfg "github.com/ibp-network/ibp-geodns-libs/config"
+)
+
+// fakeClock only moves when Advance is called. It counts After calls so tests
+// can wait for the dispatcher to go back to sleep instead of sleeping themselves.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+	calls   int
+	called  chan struct{} // closed and replaced on every After call
+}
+
+type fakeWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), called: make(chan struct{})}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	close(c.called)
+	c.called = make(chan struct{})
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, fakeWaiter{at: c.now.Add(d), ch: ch})
+	return ch
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	kept := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			kept = append(kept, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = kept
+}
+
+// Calls returns how many times After has been called so far.
+func (c *fakeClock) Calls() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls
+}
+
+// AwaitAfter blocks until After has been called more than seen times, which
+// the dispatcher does right before it sleeps waiting for an item to be due.
+func (c *fakeClock) AwaitAfter(t *testing.T, seen int) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		c.mu.Lock()
+		calls, called := c.calls, c.called
+		c.mu.Unlock()
+		if calls > seen {
+			return
+		}
+		select {
+		case <-called:
+		case <-timeout:
+			t.Fatalf("timed out waiting for the dispatcher to sleep on the clock")
+		}
+	}
+}
+
+// schedulerCheck registers a site check that records each run and, when
+// gate is non-nil, blocks until gate is closed.
+func schedulerCheck(t *testing.T, name string, gate chan struct{}) *atomic.Int64 {
+	t.Helper()
+	var runs atomic.Int64
+	RegisterSiteCheck(name, func(ctx context.Context, check cfg.Check, member cfg.Member) {
+		runs.Add(1)
+		if gate != nil {
+			<-gate
+		}
+	})
+	t.Cleanup(func() { delete(CheckRegistry.Site, name) })
+	return &runs
+}
+
+func startTestScheduler(t *testing.T, cm *CheckManager, check string, lastExecuted time.Time, interval time.Duration, members ...string) {
+	t.Helper()
+	for _, name := range members {
+		m := cfg.Member{}
+		m.Details.Name = name
+		cm.checkQueue.Add(&CheckItem{
+			Type:            "site",
+			Check:           cfg.Check{Name: check},
+			Member:          m,
+			LastExecuted:    lastExecuted,
+			MinimumInterval: interval,
+			Generation:      cm.currentGeneration(),
+		})
+	}
+	cm.startScheduler()
+	t.Cleanup(func() {
+		close(cm.shutdownCh)
+		cm.wg.Wait()
+	})
+}
+
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSchedulerRunsOverdueItemsUpToPoolSize(t *testing.T) {
+	gate := make(chan struct{})
+	runs := schedulerCheck(t, "test-sched-pool", gate)
+	clk := newFakeClock()
+	cm := newCheckManager(2, InitOptions{}, clk)
+	startTestScheduler(t, cm, "test-sched-pool", clk.Now().Add(-time.Hour), time.Minute, "a", "b", "c")
+
+	eventually(t, "two concurrent runs", func() bool { return runs.Load() == 2 })
+	// Both workers are held by the gate, so the third item must stay queued.
+	if got, queued := cm.inFlight.Load(), cm.checkQueue.Count(); got != 2 || queued != 1 {
+		t.Fatalf("expected the pool of 2 to bound concurrency, got %d in flight and %d queued", got, queued)
+	}
+
+	close(gate)
+	eventually(t, "third run once a worker is free", func() bool { return runs.Load() == 3 })
+}
+
+func TestSchedulerSleepsUntilEarliestItemIsDue(t *testing.T) {
+	runs := schedulerCheck(t, "test-sched-due", nil)
+	clk := newFakeClock()
+	cm := newCheckManager(4, InitOptions{}, clk)
+	startTestScheduler(t, cm, "test-sched-due", clk.Now(), time.Minute, "a")
+
+	clk.AwaitAfter(t, 0)
+	if got := runs.Load(); got != 0 {
+		t.Fatalf("expected no run before the interval elapsed, got %d", got)
+	}
+
+	clk.Advance(59 * time.Second)
+	if got := runs.Load(); got != 0 {
+		t.Fatalf("expected no run at 59s, got %d", got)
+	}
+
+	seen := clk.Calls()
+	clk.Advance(time.Second)
+	eventually(t, "run once due", func() bool { return runs.Load() == 1 })
+
+	// The finished item is requeued for the next interval, not rerun at once.
+	clk.AwaitAfter(t, seen)
+	if got := runs.Load(); got != 1 {
+		t.Fatalf("expected one run per interval, got %d", got)
+	}
+	clk.Advance(time.Minute)
+	eventually(t, "second run", func() bool { return runs.Load() == 2 })
+}
+
+func TestSchedulerWakesForItemsAddedWhileIdle(t *testing.T) {
+	runs := schedulerCheck(t, "test-sched-wake", nil)
+	clk := newFakeClock()
+	cm := newCheckManager(2, InitOptions{}, clk)
+	startTestScheduler(t, cm, "test-sched-wake", clk.Now(), time.Hour, "later")
+
+	// The dispatcher sleeps until the hourly item is due; a new item must wake it.
+	clk.AwaitAfter(t, 0)
+	cm.checkQueue.Add(&CheckItem{
+		Type:            "site",
+		Check:           cfg.Check{Name: "test-sched-wake"},
+		LastExecuted:    clk.Now().Add(-2 * time.Hour),
+		MinimumInterval: time.Hour,
+		Generation:      cm.currentGeneration(),
+	})
+	cm.signal()
+	eventually(t, "run of the new item", func() bool { return runs.Load() == 1 })
+}
+
+func TestSchedulerAppliesGlobalRate(t *testing.T) {
+	runs := schedulerCheck(t, "test-sched-rate", nil)
+	clk := newFakeClock()
+	cm := newCheckManager(8, InitOptions{MaxChecksPerSecond: 2}, clk) // one dispatch per 500ms
+	startTestScheduler(t, cm, "test-sched-rate", clk.Now().Add(-time.Hour), time.Hour, "a", "b", "c", "d")
+
+	eventually(t, "first run", func() bool { return runs.Load() == 1 })
+	clk.AwaitAfter(t, 0) // waiting for the next rate slot
+	if got := runs.Load(); got != 1 {
+		t.Fatalf("expected the rate to hold back further runs, got %d", got)
+	}
+
+	for want := int64(2); want <= 4; want++ {
+		clk.Advance(500 * time.Millisecond)
+		eventually(t, "next rated run", func() bool { return runs.Load() == want })
+	}
+}
+
+func TestRateInterval(t *testing.T) {
+	if got := rateInterval(0); got != 0 {
+		t.Fatalf("expected no interval for an unlimited rate, got %v", got)
+	}
+	if got := rateInterval(4); got != 250*time.Millisecond {
+		t.Fatalf("expected 250ms for 4/s, got %v", got)
+	}
+}
+
+func TestScheduleJitterIsStablePerNodeAndBounded(t *testing.T) {
+	const interval = 10 * time.Minute
+	a := scheduleJitter("node-a", "site|ping|member", interval, 10)
+	if again := scheduleJitter("node-a", "site|ping|member", interval, 10); again != a {
+		t.Fatalf("expected the same jitter for the same node and item, got %v and %v", a, again)
+	}
+	if b := scheduleJitter("node-b", "site|ping|member", interval, 10); b == a {
+		t.Fatalf("expected different nodes to get different jitter, both got %v", a)
+	}
+	if got := scheduleJitter("node-a", "site|ping|member", interval, 0); got != 0 {
+		t.Fatalf("expected no jitter at 0%%, got %v", got)
+	}
+
+	for i := 0; i < 200; i++ {
+		got := scheduleJitter("node-a", fmt.Sprintf("item-%d", i), interval, 10)
+		if got < 0 || got >= time.Minute {
+			t.Fatalf("jitter %v outside [0, 1m) for 10%% of %v", got, interval)
+		}
+	}
+}
+
+func TestSchedulerDelaysFirstRunByJitter(t *testing.T) {
+	runs := schedulerCheck(t, "test-sched-jitter", nil)
+	clk := newFakeClock()
+	cm := newCheckManager(2, InitOptions{NodeID: "node-a", JitterPercent: 50}, clk)
+
+	m := cfg.Member{}
+	m.Details.Name = "a"
+	item := &CheckItem{
+		Type:            "site",
+		Check:           cfg.Check{Name: "test-sched-jitter"},
+		Member:          m,
+		MinimumInterval: time.Hour,
+		Generation:      cm.currentGeneration(),
+	}
+	cm.applyJitter(item, clk.Now())
+	if item.Jitter <= 0 || item.Jitter >= 30*time.Minute {
+		t.Fatalf("expected jitter in (0, 30m), got %v", item.Jitter)
+	}
+	startTestScheduler(t, cm, "test-sched-jitter", clk.Now(), time.Hour)
+	cm.checkQueue.Add(item)
+	cm.signal()
+
+	clk.AwaitAfter(t, 0)
+	if got := runs.Load(); got != 0 {
+		t.Fatalf("expected a never-run item to wait for its jitter, got %d runs", got)
+	}
+
+	seen := clk.Calls()
+	clk.Advance(item.Jitter)
+	eventually(t, "first run after jitter", func() bool { return runs.Load() == 1 })
+
+	// The next run is exactly one interval later, keeping the jittered phase.
+	clk.AwaitAfter(t, seen)
+	clk.Advance(time.Hour - time.Second)
+	if got := runs.Load(); got != 1 {
+		t.Fatalf("expected the second run to wait for the interval, got %d runs", got)
+	}
+	clk.Advance(time.Second)
+	eventually(t, "second run", func() bool { return runs.Load() == 2 })
+}
@@ -0,0 +1,259 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	cfg "github.com/ibp-network/ibp-geodns-libs/config"
+	dat "github.com/ibp-network/ibp-geodns-libs/data"
+	log "github.com/ibp-network/ibp-geodns-libs/logging"
+)
+
+// managerState is the snapshot kept at InitOptions.StatePath so a restarted
+// monitor resumes its schedule and local results instead of starting empty.
+type managerState struct {
+	Saved     time.Time
+	LastRuns  map[string]time.Time
+	Sites     []dat.SiteResult
+	Domains   []dat.DomainResult
+	Endpoints []dat.EndpointResult
+}
+
+// persistState saves the snapshot every stateInterval until shutdown. The
+// final save happens in Stop, after the workers have finished.
+func (cm *CheckManager) persistState() {
+	defer cm.wg.Done()
+
+	ticker := time.NewTicker(cm.stateInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			cm.saveState()
+		case <-cm.shutdownCh:
+			return
+		}
+	}
+}
+
+func (cm *CheckManager) saveState() {
+	cm.lastRunsMu.Lock()
+	lastRuns := make(map[string]time.Time, len(cm.lastRuns))
+	for k, t := range cm.lastRuns {
+		lastRuns[k] = t
+	}
+	cm.lastRunsMu.Unlock()
+
+	sites, domains, endpoints := dat.GetLocalResults()
+	st := managerState{
+		Saved:     cm.now(),
+		LastRuns:  lastRuns,
+		Sites:     sites,
+		Domains:   domains,
+		Endpoints: endpoints,
+	}
+	cm.filterStateResults(&st)
+	if err := writeState(cm.statePath, st); err != nil {
+		log.Log(log.Warn, "Monitor state not saved: %v", err)
+	}
+}
+
+// filterStateResults keeps only results for scheduled items that are younger
+// than stateMaxAge. The local caches stamp restored results with the time
+// they were restored, so those that no live result has replaced yet are
+// given back their original Checktime first; otherwise they would never age.
+func (cm *CheckManager) filterStateResults(st *managerState) {
+	cutoff := st.Saved.Add(-cm.stateMaxAge)
+
+	cm.claimMu.Lock()
+	defer cm.claimMu.Unlock()
+	cm.restoredMu.Lock()
+	defer cm.restoredMu.Unlock()
+
+	keep := func(checkType, checkName, domain, endpoint string, ipv6 bool, r *dat.Result) bool {
+		if !cm.isScheduledLocked(checkType, checkName, r.Member, domain, endpoint) {
+			return false
+		}
+		if t, ok := cm.restored[localStatusKey(checkType, checkName, r.Member.Details.Name, domain, endpoint, ipv6)]; ok {
+			r.Checktime = t
+		}
+		return !r.Checktime.Before(cutoff)
+	}
+
+	sites := st.Sites[:0]
+	for _, s := range st.Sites {
+		s.Results = filterResults(s.Results, func(r *dat.Result) bool {
+			return keep("site", s.Check.Name, "", "", s.IsIPv6, r)
+		})
+		if len(s.Results) > 0 {
+			sites = append(sites, s)
+		}
+	}
+	st.Sites = sites
+
+	domains := st.Domains[:0]
+	for _, d := range st.Domains {
+		d.Results = filterResults(d.Results, func(r *dat.Result) bool {
+			return keep("domain", d.Check.Name, d.Domain, "", d.IsIPv6, r)
+		})
+		if len(d.Results) > 0 {
+			domains = append(domains, d)
+		}
+	}
+	st.Domains = domains
+
+	endpoints := st.Endpoints[:0]
+	for _, e := range st.Endpoints {
+		e.Results = filterResults(e.Results, func(r *dat.Result) bool {
+			return keep("endpoint", e.Check.Name, e.Domain, e.RpcUrl, e.IsIPv6, r)
+		})
+		if len(e.Results) > 0 {
+			endpoints = append(endpoints, e)
+		}
+	}
+	st.Endpoints = endpoints
+}
+
+func filterResults(results []dat.Result, keep func(*dat.Result) bool) []dat.Result {
+	out := make([]dat.Result, 0, len(results))
+	for _, r := range results {
+		if keep(&r) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// isScheduledLocked reports whether a result belongs to an item in cm.items.
+// Callers hold claimMu.
+func (cm *CheckManager) isScheduledLocked(checkType, checkName string, member cfg.Member, domain, endpoint string) bool {
+	_, ok := cm.items[itemKey(&CheckItem{
+		Type:     checkType,
+		Check:    cfg.Check{Name: checkName},
+		Member:   member,
+		Domain:   domain,
+		Endpoint: endpoint,
+	})]
+	return ok
+}
+
+// restoreState loads the snapshot written by a previous run and restores
+// last-run times younger than stateMaxAge. It runs before the queue is built
+// so the restored times are applied to the new items.
+func (cm *CheckManager) restoreState() managerState {
+	st, err := readState(cm.statePath)
+	if err != nil {
+		log.Log(log.Warn, "Monitor state not restored: %v", err)
+		return managerState{}
+	}
+	if st.Saved.IsZero() {
+		return st
+	}
+
+	cutoff := cm.now().Add(-cm.stateMaxAge)
+	runs := 0
+	cm.lastRunsMu.Lock()
+	for k, t := range st.LastRuns {
+		if !t.Before(cutoff) {
+			cm.lastRuns[k] = t
+			runs++
+		}
+	}
+	cm.lastRunsMu.Unlock()
+
+	log.Log(log.Info, "Restored %d last-run times saved %s ago", runs, cm.now().Sub(st.Saved).Round(time.Second))
+	return st
+}
+
+// restoreResults feeds the saved results back into the local result caches
+// without proposing or notifying, and seeds transition tracking so the first
+// fresh result is compared against them. It runs once the queue is built:
+// results older than stateMaxAge or for items no longer scheduled are
+// discarded.
+func (cm *CheckManager) restoreResults(st managerState) {
+	cm.filterStateResults(&st)
+
+	cm.restoredMu.Lock()
+	defer cm.restoredMu.Unlock()
+
+	results := 0
+	for _, s := range st.Sites {
+		for _, r := range s.Results {
+			dat.UpdateLocalSiteResult(s.Check, r.Member, r.Status, r.ErrorText, r.Data, s.IsIPv6)
+			cm.markRestoredLocked("site", s.Check.Name, r.Member.Details.Name, "", "", s.IsIPv6, r)
+			results++
+		}
+	}
+	for _, d := range st.Domains {
+		for _, r := range d.Results {
+			dat.UpdateLocalDomainResult(d.Check, r.Member, d.Service, d.Domain, r.Status, r.ErrorText, r.Data, d.IsIPv6)
+			cm.markRestoredLocked("domain", d.Check.Name, r.Member.Details.Name, d.Domain, "", d.IsIPv6, r)
+			results++
+		}
+	}
+	for _, e := range st.Endpoints {
+		for _, r := range e.Results {
+			dat.UpdateLocalEndpointResult(e.Check, r.Member, e.Service, e.Domain, e.RpcUrl, r.Status, r.ErrorText, r.Data, e.IsIPv6)
+			cm.markRestoredLocked("endpoint", e.Check.Name, r.Member.Details.Name, e.Domain, e.RpcUrl, e.IsIPv6, r)
+			results++
+		}
+	}
+	if results > 0 {
+		log.Log(log.Info, "Restored %d local results", results)
+	}
+}
+
+// markRestoredLocked remembers a restored result's original Checktime until a
+// live result replaces it, and records its status for trackLocalTransitions
+// unless a live result has already arrived. Callers hold restoredMu.
+func (cm *CheckManager) markRestoredLocked(checkType, checkName, member, domain, endpoint string, ipv6 bool, r dat.Result) {
+	key := localStatusKey(checkType, checkName, member, domain, endpoint, ipv6)
+	cm.restored[key] = r.Checktime
+	localStatuses.LoadOrStore(key, r.Status)
+}
+
+// forgetRestored drops the original Checktime of a result replaced by a live
+// one. Inconclusive results replace nothing.
+func (cm *CheckManager) forgetRestored(r CheckResult) {
+	if r.Inconclusive {
+		return
+	}
+	cm.restoredMu.Lock()
+	defer cm.restoredMu.Unlock()
+	delete(cm.restored, localStatusKey(r.Type, r.CheckName, r.Member, r.Domain, r.Endpoint, r.IsIPv6))
+}
+
+func readState(path string) (managerState, error) {
+	var st managerState
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return st, nil
+		}
+		return st, fmt.Errorf("read state: %w", err)
+	}
+	if err := json.Unmarshal(raw, &st); err != nil {
+		return managerState{}, fmt.Errorf("parse state: %w", err)
+	}
+	return st, nil
+}
+
+// writeState replaces the file at path atomically.
+func writeState(path string, st managerState) error {
+	raw, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
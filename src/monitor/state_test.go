@@ -0,0 +1,133 @@
+package monitor
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	cfg "github.com/ibp-network/ibp-geodns-libs/config"
+	dat "github.com/ibp-network/ibp-geodns-libs/data"
+)
+
+func TestStateRoundTripDiscardsStaleEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "monitor-state.json")
+	clk := newFakeClock()
+	now := clk.Now()
+
+	saver := newCheckManager(1, InitOptions{StatePath: path}, clk)
+	saver.lastRuns["fresh"] = now.Add(-5 * time.Minute)
+	saver.lastRuns["stale"] = now.Add(-2 * time.Hour)
+	saver.saveState()
+
+	restored := newCheckManager(1, InitOptions{StatePath: path, StateMaxAge: time.Hour}, clk)
+	restored.restoreState()
+	if len(restored.lastRuns) != 1 || !restored.lastRuns["fresh"].Equal(now.Add(-5*time.Minute)) {
+		t.Fatalf("expected only the fresh last run to be restored, got %v", restored.lastRuns)
+	}
+}
+
+func clearLocalStatuses(t *testing.T) {
+	localStatuses.Range(func(k, _ any) bool { localStatuses.Delete(k); return true })
+	t.Cleanup(func() { localStatuses.Range(func(k, _ any) bool { localStatuses.Delete(k); return true }) })
+}
+
+func siteState(saved time.Time, results ...dat.Result) managerState {
+	return managerState{
+		Saved: saved,
+		Sites: []dat.SiteResult{{Check: cfg.Check{Name: "ping"}, Results: results}},
+	}
+}
+
+func TestRestoreResultsSeedsLocalTransitions(t *testing.T) {
+	clearLocalStatuses(t)
+	clk := newFakeClock()
+	now := clk.Now()
+	c := reloadTestConfig(60, "alpha")
+
+	cm := newCheckManager(1, InitOptions{StatePath: "unused", StateMaxAge: time.Hour}, clk)
+	cm.initializeChecks(c)
+	cm.restoreResults(siteState(now.Add(-time.Minute),
+		dat.Result{Member: c.Members["alpha"], Status: false, Checktime: now.Add(-2 * time.Minute)}))
+
+	var events []StatusEvent
+	remove := AddStatusListener(func(ev StatusEvent) { events = append(events, ev) })
+	defer remove()
+	trackLocalTransitions(CheckResult{Type: "site", CheckName: "ping", Member: "alpha", Status: true})
+	if len(events) != 1 || events[0].Kind != StatusEventTransition {
+		t.Fatalf("expected the first live result to transition from the restored status, got %#v", events)
+	}
+}
+
+func TestRestoreResultsDiscardsStaleAndRemovedTargets(t *testing.T) {
+	clearLocalStatuses(t)
+	clk := newFakeClock()
+	now := clk.Now()
+	old := reloadTestConfig(60, "alpha", "removed", "stale")
+	path := filepath.Join(t.TempDir(), "monitor-state.json")
+	if err := writeState(path, siteState(now.Add(-time.Minute),
+		dat.Result{Member: old.Members["alpha"], Checktime: now.Add(-2 * time.Minute)},
+		dat.Result{Member: old.Members["removed"], Checktime: now.Add(-2 * time.Minute)},
+		dat.Result{Member: old.Members["stale"], Checktime: now.Add(-3 * time.Hour)},
+	)); err != nil {
+		t.Fatalf("writeState: %v", err)
+	}
+
+	// "removed" is no longer in the config this monitor restarts with.
+	cm := newCheckManager(1, InitOptions{StatePath: path, StateMaxAge: time.Hour}, clk)
+	saved := cm.restoreState()
+	cm.initializeChecks(reloadTestConfig(60, "alpha", "stale"))
+	cm.restoreResults(saved)
+
+	for member, want := range map[string]bool{"alpha": true, "removed": false, "stale": false} {
+		if _, ok := localStatuses.Load(localStatusKey("site", "ping", member, "", "", false)); ok != want {
+			t.Fatalf("restored %s: got %v, want %v", member, ok, want)
+		}
+	}
+	if len(cm.restored) != 1 {
+		t.Fatalf("expected one restored result, got %v", cm.restored)
+	}
+}
+
+func TestSaveKeepsOriginalChecktimeOfRestoredResults(t *testing.T) {
+	clearLocalStatuses(t)
+	clk := newFakeClock()
+	c := reloadTestConfig(60, "alpha")
+
+	cm := newCheckManager(1, InitOptions{StatePath: "unused", StateMaxAge: time.Hour}, clk)
+	cm.initializeChecks(c)
+	original := clk.Now().Add(-50 * time.Minute)
+	cm.restoreResults(siteState(clk.Now(), dat.Result{Member: c.Members["alpha"], Checktime: original}))
+
+	// The local cache stamps the restored result with the restore time.
+	clk.Advance(5 * time.Minute)
+	st := siteState(clk.Now(), dat.Result{Member: c.Members["alpha"], Checktime: clk.Now().Add(-5 * time.Minute)})
+	cm.filterStateResults(&st)
+	if len(st.Sites) != 1 || !st.Sites[0].Results[0].Checktime.Equal(original) {
+		t.Fatalf("expected the original Checktime to be saved, got %#v", st.Sites)
+	}
+
+	// Without a live result it expires on its original schedule.
+	clk.Advance(10 * time.Minute)
+	st = siteState(clk.Now(), dat.Result{Member: c.Members["alpha"], Checktime: clk.Now().Add(-15 * time.Minute)})
+	cm.filterStateResults(&st)
+	if len(st.Sites) != 0 {
+		t.Fatalf("expected the expired restored result to be dropped, got %#v", st.Sites)
+	}
+
+	// A live result replaces it and is saved with its own time.
+	cm.forgetRestored(CheckResult{Type: "site", CheckName: "ping", Member: "alpha"})
+	live := clk.Now()
+	st = siteState(clk.Now(), dat.Result{Member: c.Members["alpha"], Checktime: live})
+	cm.filterStateResults(&st)
+	if len(st.Sites) != 1 || !st.Sites[0].Results[0].Checktime.Equal(live) {
+		t.Fatalf("expected the live result to be kept, got %#v", st.Sites)
+	}
+}
+
+func TestRestoreStateWithoutFile(t *testing.T) {
+	cm := newCheckManager(1, InitOptions{StatePath: filepath.Join(t.TempDir(), "missing.json"), StateMaxAge: time.Hour}, newFakeClock())
+	cm.restoreState()
+	if len(cm.lastRuns) != 0 {
+		t.Fatalf("expected nothing restored, got %v", cm.lastRuns)
+	}
+}
@@ -13,3 +13,24 @@ type Result struct {
 	ErrorText string
 	Data      map[string]interface{}
 }
+
+// CheckResult is one local observation as recorded by the Update*ResultLocal
+// helpers, delivered to listeners registered with AddResultListener.
+type CheckResult struct {
+	Type      string // "site", "domain", "endpoint"
+	CheckName string
+	Member    string
+	Service   string // service network name; empty for site checks
+	Domain    string
+	Endpoint  string
+	IsIPv6    bool
+	Status    bool
+	ErrorText string
+	Data      map[string]interface{}
+	Checktime time.Time
+	Duration  time.Duration // time spent producing this result within its run
+
+	// Inconclusive marks a run that could not reach a verdict. Status is false,
+	// ErrorText holds the reason, and nothing was stored or proposed.
+	Inconclusive bool
+}
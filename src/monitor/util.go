@@ -1,12 +1,18 @@
 package monitor
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"net"
+	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
+
+	log "github.com/ibp-network/ibp-geodns-libs/logging"
 )
 
 type CheckTarget struct {
@@ -22,6 +28,46 @@ func (t CheckTarget) DialAddress(ip string) string {
 	return net.JoinHostPort(ip, t.Port)
 }
 
+// newPinnedHTTPClient returns a client that sends every request for target to
+// ip while keeping the target hostname for SNI, certificate checks and Host.
+// Redirects to another host are not followed, since the dial would still go to
+// ip and the member would be judged on a response meant to come from elsewhere.
+func newPinnedHTTPClient(target CheckTarget, ip string, timeoutSec int, label string) *http.Client {
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{
+			ServerName: target.Hostname,
+		},
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			// addr will be "domain:port", we replace with "ip:port"
+			host, port, err := net.SplitHostPort(addr)
+			if err != nil {
+				port = target.Port
+			}
+
+			log.Log(log.Debug, "%s dial: intercepting %s:%s => %s:%s", label, host, port, ip, port)
+
+			dialer := &net.Dialer{
+				Timeout: time.Duration(timeoutSec) * time.Second,
+			}
+			return dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+		},
+	}
+
+	return &http.Client{
+		Timeout:   time.Duration(timeoutSec) * time.Second,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if !strings.EqualFold(req.URL.Hostname(), target.Hostname) {
+				return fmt.Errorf("redirect to %s not followed: host differs from %s", req.URL.Host, target.Hostname)
+			}
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			return nil
+		},
+	}
+}
+
 func getIntOption(extraOptions map[string]interface{}, key string, defaultValue int) int {
 	if extraOptions == nil {
 		return defaultValue
@@ -72,6 +118,52 @@ func getFloatOption(extraOptions map[string]interface{}, key string, defaultValu
 	return defaultValue
 }
 
+func getStringOption(extraOptions map[string]interface{}, key string, defaultValue string) string {
+	if extraOptions == nil {
+		return defaultValue
+	}
+	if val, ok := extraOptions[key].(string); ok {
+		return val
+	}
+	return defaultValue
+}
+
+func getStringMapOption(extraOptions map[string]interface{}, key string) map[string]string {
+	out := make(map[string]string)
+	if extraOptions == nil {
+		return out
+	}
+	raw, ok := extraOptions[key].(map[string]interface{})
+	if !ok {
+		return out
+	}
+	for k, v := range raw {
+		if str, ok := v.(string); ok {
+			out[k] = str
+		} else {
+			out[k] = fmt.Sprint(v)
+		}
+	}
+	return out
+}
+
+func getStringListOption(extraOptions map[string]interface{}, key string) []string {
+	if extraOptions == nil {
+		return nil
+	}
+	raw, ok := extraOptions[key].([]interface{})
+	if !ok {
+		return nil
+	}
+	out := make([]string, 0, len(raw))
+	for _, v := range raw {
+		if str, ok := v.(string); ok && strings.TrimSpace(str) != "" {
+			out = append(out, strings.TrimSpace(str))
+		}
+	}
+	return out
+}
+
 func parseCheckTarget(raw string, defaultScheme string) (CheckTarget, error) {
 	raw = strings.TrimSpace(raw)
 	if raw == "" {
@@ -2,6 +2,10 @@ package monitor
 
 import (
 	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -78,3 +82,36 @@ func TestParseChainIDSupportsHexAndDecimal(t *testing.T) {
 		t.Fatalf("expected invalid chain id to fail parsing")
 	}
 }
+
+func TestPinnedHTTPClientRejectsCrossHostRedirects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/same":
+			http.Redirect(w, r, "/health", http.StatusFound)
+		case "/other":
+			http.Redirect(w, r, "http://elsewhere.example.net/health", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer srv.Close()
+
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	target := CheckTarget{Scheme: "http", Hostname: "rpc.example.net", Port: port}
+	client := newPinnedHTTPClient(target, "127.0.0.1", 5, "test")
+	defer client.CloseIdleConnections()
+
+	resp, err := client.Get("http://rpc.example.net:" + port + "/same")
+	if err != nil {
+		t.Fatalf("expected same-host redirect to be followed, got %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Request.URL.Path != "/health" {
+		t.Fatalf("expected redirect to /health, got %d %s", resp.StatusCode, resp.Request.URL.Path)
+	}
+
+	_, err = client.Get("http://rpc.example.net:" + port + "/other")
+	if err == nil || !strings.Contains(err.Error(), "not followed") {
+		t.Fatalf("expected cross-host redirect to be rejected, got %v", err)
+	}
+}
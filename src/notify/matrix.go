@@ -0,0 +1,318 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/ibp-network/ibp-geodns-libs/logging"
+
+	"github.com/ibp-network/ibp-geodns-monitor/src/monitor"
+	"github.com/ibp-network/ibp-geodns-monitor/src/settings"
+)
+
+// maxPendingPerMember bounds the events held for one member while it is rate-limited.
+const maxPendingPerMember = 50
+
+// stopFlushTimeout bounds how long Stop spends sending the batches still pending.
+const stopFlushTimeout = 5 * time.Second
+
+// Matrix posts status events to a Matrix room, one message per member batch.
+// It calls the client-server API directly rather than through mautrix: a
+// notice is a single PUT, and handling 429 and 5xx responses here lets a
+// deferred batch go back into the per-member queue instead of being retried
+// inside the call.
+type Matrix struct {
+	homeserver  string
+	token       string
+	roomID      string
+	source      string
+	events      map[string]bool
+	batch       time.Duration
+	minInterval time.Duration
+	client      *http.Client
+	now         func() time.Time
+
+	mu         sync.Mutex
+	pending    map[string]*memberBatch
+	lastSent   map[string]time.Time
+	retryAfter time.Time
+	txn        int64
+
+	stopCh chan struct{}
+	done   chan struct{}
+}
+
+type memberBatch struct {
+	first   time.Time
+	events  []monitor.StatusEvent
+	dropped int
+}
+
+// NewMatrix returns a notifier for s. source names this monitor in messages.
+func NewMatrix(s settings.MatrixSettings, source string) *Matrix {
+	kinds := s.Events
+	if len(kinds) == 0 {
+		kinds = []string{monitor.StatusEventTransition, monitor.StatusEventProposal}
+	}
+	events := make(map[string]bool, len(kinds))
+	for _, k := range kinds {
+		events[strings.ToLower(k)] = true
+	}
+
+	return &Matrix{
+		homeserver:  strings.TrimRight(s.Homeserver, "/"),
+		token:       s.AccessToken,
+		roomID:      s.RoomID,
+		source:      source,
+		events:      events,
+		batch:       time.Duration(s.BatchSeconds) * time.Second,
+		minInterval: time.Duration(s.MinIntervalSeconds) * time.Second,
+		client:      &http.Client{Timeout: 15 * time.Second},
+		now:         time.Now,
+		pending:     make(map[string]*memberBatch),
+		lastSent:    make(map[string]time.Time),
+	}
+}
+
+// Handle queues ev for its member's next message.
+func (m *Matrix) Handle(ev monitor.StatusEvent) {
+	if !m.events[ev.Kind] {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, ok := m.pending[ev.Member]
+	if !ok {
+		b = &memberBatch{first: m.now()}
+		m.pending[ev.Member] = b
+	}
+	if len(b.events) >= maxPendingPerMember {
+		b.events = b.events[1:]
+		b.dropped++
+	}
+	b.events = append(b.events, ev)
+}
+
+// Start flushes due batches every tick until Stop.
+func (m *Matrix) Start(tick time.Duration) {
+	m.stopCh = make(chan struct{})
+	m.done = make(chan struct{})
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.flushDue(m.now())
+			case <-m.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the flush loop, then sends the batches still waiting on their
+// window or rate limit, giving up after stopFlushTimeout.
+func (m *Matrix) Stop() {
+	if m.stopCh == nil {
+		return
+	}
+	close(m.stopCh)
+	<-m.done
+
+	ctx, cancel := context.WithTimeout(context.Background(), stopFlushTimeout)
+	defer cancel()
+	m.flushAll(ctx)
+}
+
+// flushAll sends every pending batch regardless of its window and rate limit.
+// Batches that fail, or are still unsent when ctx ends, are dropped.
+func (m *Matrix) flushAll(ctx context.Context) {
+	m.mu.Lock()
+	pending := m.pending
+	m.pending = make(map[string]*memberBatch)
+	m.mu.Unlock()
+
+	members := make([]string, 0, len(pending))
+	for member := range pending {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+	for _, member := range members {
+		if err := m.send(ctx, formatMatrixMessage(m.source, member, pending[member])); err != nil {
+			log.Log(log.Warn, "Matrix notification for %s dropped at shutdown: %v", member, err)
+		}
+	}
+}
+
+// flushDue sends every batch whose collection window has passed and whose
+// member is not rate-limited.
+func (m *Matrix) flushDue(now time.Time) {
+	type due struct {
+		member string
+		batch  *memberBatch
+	}
+
+	m.mu.Lock()
+	if now.Before(m.retryAfter) {
+		m.mu.Unlock()
+		return
+	}
+	var ready []due
+	for member, b := range m.pending {
+		if now.Sub(b.first) < m.batch {
+			continue
+		}
+		if last, ok := m.lastSent[member]; ok && now.Sub(last) < m.minInterval {
+			continue
+		}
+		ready = append(ready, due{member, b})
+		delete(m.pending, member)
+	}
+	m.mu.Unlock()
+
+	sort.Slice(ready, func(i, j int) bool { return ready[i].member < ready[j].member })
+	for i, d := range ready {
+		err := m.send(context.Background(), formatMatrixMessage(m.source, d.member, d.batch))
+		if err == nil {
+			m.mu.Lock()
+			m.lastSent[d.member] = now
+			m.mu.Unlock()
+			continue
+		}
+
+		var retry *retryableError
+		if !errors.As(err, &retry) {
+			log.Log(log.Warn, "Matrix notification for %s dropped: %v", d.member, err)
+			continue
+		}
+
+		// Put this and every unsent batch back and wait out the server.
+		log.Log(log.Warn, "Matrix notification for %s deferred: %v", d.member, err)
+		m.mu.Lock()
+		m.retryAfter = now.Add(retry.after)
+		for _, r := range ready[i:] {
+			m.requeueLocked(r.member, r.batch)
+		}
+		m.mu.Unlock()
+		return
+	}
+}
+
+// requeueLocked merges b in front of anything queued for member since it was taken.
+func (m *Matrix) requeueLocked(member string, b *memberBatch) {
+	if cur, ok := m.pending[member]; ok {
+		b.events = append(b.events, cur.events...)
+		b.dropped += cur.dropped
+	}
+	if over := len(b.events) - maxPendingPerMember; over > 0 {
+		b.events = b.events[over:]
+		b.dropped += over
+	}
+	m.pending[member] = b
+}
+
+type retryableError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+
+func (m *Matrix) send(ctx context.Context, body string) error {
+	m.mu.Lock()
+	m.txn++
+	txnID := fmt.Sprintf("ibpmonitor-%d-%d", m.now().UnixNano(), m.txn)
+	m.mu.Unlock()
+
+	payload, err := json.Marshal(map[string]string{"msgtype": "m.notice", "body": body})
+	if err != nil {
+		return err
+	}
+
+	endpoint := m.homeserver + "/_matrix/client/v3/rooms/" + url.PathEscape(m.roomID) +
+		"/send/m.room.message/" + url.PathEscape(txnID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+m.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return &retryableError{err: err, after: time.Minute}
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests:
+		var limited struct {
+			RetryAfterMs int64 `json:"retry_after_ms"`
+		}
+		_ = json.Unmarshal(respBody, &limited)
+		after := time.Duration(limited.RetryAfterMs) * time.Millisecond
+		if after <= 0 {
+			after = time.Minute
+		}
+		return &retryableError{err: fmt.Errorf("rate limited by homeserver"), after: after}
+	case resp.StatusCode >= 500:
+		return &retryableError{err: fmt.Errorf("homeserver error %d", resp.StatusCode), after: time.Minute}
+	default:
+		return fmt.Errorf("homeserver rejected message: %d %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+}
+
+// formatMatrixMessage renders one member's batch as a plain-text notice.
+func formatMatrixMessage(source, member string, b *memberBatch) string {
+	var sb strings.Builder
+	count := len(b.events) + b.dropped
+	noun := "status changes"
+	if count == 1 {
+		noun = "status change"
+	}
+	fmt.Fprintf(&sb, "[%s] %s: %d %s", source, member, count, noun)
+	if b.dropped > 0 {
+		fmt.Fprintf(&sb, " (%d oldest omitted)", b.dropped)
+	}
+
+	for _, ev := range b.events {
+		state := "UP"
+		if !ev.Status {
+			state = "DOWN"
+		}
+		family := "IPv4"
+		if ev.IsIPv6 {
+			family = "IPv6"
+		}
+		target := ev.Endpoint
+		if target == "" {
+			target = ev.Domain
+		}
+		if target == "" {
+			target = "site"
+		}
+
+		fmt.Fprintf(&sb, "\n- %s %s %s %s %s (%s)", state, ev.CheckName, ev.Type, target, family, ev.Kind)
+		if ev.ErrorText != "" {
+			fmt.Fprintf(&sb, ": %s", ev.ErrorText)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,194 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ibp-network/ibp-geodns-monitor/src/monitor"
+	"github.com/ibp-network/ibp-geodns-monitor/src/settings"
+)
+
+// fakeHomeserver records m.room.message bodies and answers with statuses in order.
+type fakeHomeserver struct {
+	mu       sync.Mutex
+	paths    []string
+	bodies   []string
+	statuses []int
+}
+
+func (h *fakeHomeserver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if r.Method != http.MethodPut || r.Header.Get("Authorization") != "Bearer secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	status := http.StatusOK
+	if len(h.statuses) > 0 {
+		status, h.statuses = h.statuses[0], h.statuses[1:]
+	}
+	if status == http.StatusTooManyRequests {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"errcode":"M_LIMIT_EXCEEDED","retry_after_ms":5000}`))
+		return
+	}
+
+	var msg map[string]string
+	_ = json.NewDecoder(r.Body).Decode(&msg)
+	h.paths = append(h.paths, r.URL.EscapedPath())
+	h.bodies = append(h.bodies, msg["body"])
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(`{"event_id":"$1"}`))
+}
+
+func (h *fakeHomeserver) messages() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.bodies...)
+}
+
+func newTestMatrix(t *testing.T, hs *fakeHomeserver, now *time.Time) *Matrix {
+	t.Helper()
+	srv := httptest.NewServer(hs)
+	t.Cleanup(srv.Close)
+
+	m := NewMatrix(settings.MatrixSettings{
+		Enabled:            1,
+		Homeserver:         srv.URL,
+		AccessToken:        "secret",
+		RoomID:             "!ops:example.org",
+		BatchSeconds:       30,
+		MinIntervalSeconds: 300,
+	}, "monitor-1")
+	m.now = func() time.Time { return *now }
+	return m
+}
+
+func downEvent(member, kind string) monitor.StatusEvent {
+	return monitor.StatusEvent{
+		Kind:      kind,
+		Type:      "endpoint",
+		CheckName: "wss",
+		Member:    member,
+		Endpoint:  "wss://rpc.example/polkadot",
+		Status:    false,
+		ErrorText: "Syncing or not enough peers",
+	}
+}
+
+func TestMatrixGroupsEventsPerMember(t *testing.T) {
+	hs := &fakeHomeserver{}
+	now := time.Unix(1_700_000_000, 0)
+	m := newTestMatrix(t, hs, &now)
+
+	m.Handle(downEvent("alpha", monitor.StatusEventTransition))
+	m.Handle(downEvent("alpha", monitor.StatusEventProposal))
+	ev := downEvent("beta", monitor.StatusEventProposal)
+	ev.IsIPv6 = true
+	m.Handle(ev)
+
+	m.flushDue(now.Add(10 * time.Second))
+	if got := hs.messages(); len(got) != 0 {
+		t.Fatalf("batch window not over yet, got %v", got)
+	}
+
+	m.flushDue(now.Add(31 * time.Second))
+	got := hs.messages()
+	if len(got) != 2 {
+		t.Fatalf("expected one message per member, got %v", got)
+	}
+	if !strings.HasPrefix(got[0], "[monitor-1] alpha: 2 status changes") ||
+		!strings.Contains(got[0], "DOWN wss endpoint wss://rpc.example/polkadot IPv4 (proposal): Syncing or not enough peers") {
+		t.Fatalf("unexpected alpha message %q", got[0])
+	}
+	if !strings.Contains(got[1], "beta: 1 status change") || !strings.Contains(got[1], "IPv6") {
+		t.Fatalf("unexpected beta message %q", got[1])
+	}
+	if !strings.HasPrefix(hs.paths[0], "/_matrix/client/v3/rooms/%21ops:example.org/send/m.room.message/") {
+		t.Fatalf("unexpected request path %q", hs.paths[0])
+	}
+}
+
+func TestMatrixRateLimitsPerMember(t *testing.T) {
+	hs := &fakeHomeserver{}
+	now := time.Unix(1_700_000_000, 0)
+	m := newTestMatrix(t, hs, &now)
+
+	m.Handle(downEvent("alpha", monitor.StatusEventProposal))
+	m.flushDue(now.Add(30 * time.Second))
+
+	now = now.Add(40 * time.Second)
+	m.Handle(downEvent("alpha", monitor.StatusEventTransition))
+	m.flushDue(now.Add(60 * time.Second))
+	if got := hs.messages(); len(got) != 1 {
+		t.Fatalf("second message should wait for MinIntervalSeconds, got %v", got)
+	}
+
+	m.flushDue(now.Add(300 * time.Second))
+	if got := hs.messages(); len(got) != 2 {
+		t.Fatalf("expected the held batch after the interval, got %v", got)
+	}
+}
+
+func TestMatrixRequeuesWhenHomeserverLimits(t *testing.T) {
+	hs := &fakeHomeserver{statuses: []int{http.StatusTooManyRequests}}
+	now := time.Unix(1_700_000_000, 0)
+	m := newTestMatrix(t, hs, &now)
+
+	m.Handle(downEvent("alpha", monitor.StatusEventProposal))
+	flushAt := now.Add(30 * time.Second)
+	m.flushDue(flushAt)
+	if got := hs.messages(); len(got) != 0 {
+		t.Fatalf("rate-limited message must not be recorded, got %v", got)
+	}
+
+	m.flushDue(flushAt.Add(2 * time.Second))
+	if got := hs.messages(); len(got) != 0 {
+		t.Fatalf("retry_after_ms must be honoured, got %v", got)
+	}
+
+	m.flushDue(flushAt.Add(6 * time.Second))
+	if got := hs.messages(); len(got) != 1 {
+		t.Fatalf("expected the requeued batch to be sent, got %v", got)
+	}
+}
+
+func TestMatrixStopSendsPendingBatches(t *testing.T) {
+	hs := &fakeHomeserver{}
+	now := time.Unix(1_700_000_000, 0)
+	m := newTestMatrix(t, hs, &now)
+
+	m.Handle(downEvent("alpha", monitor.StatusEventProposal))
+	m.flushDue(now.Add(30 * time.Second))
+
+	now = now.Add(40 * time.Second)
+	m.Handle(downEvent("alpha", monitor.StatusEventTransition))
+	m.Handle(downEvent("beta", monitor.StatusEventProposal))
+	m.Start(time.Hour)
+	m.Stop()
+
+	got := hs.messages()
+	if len(got) != 3 {
+		t.Fatalf("expected Stop to send the windowed and rate-limited batches, got %v", got)
+	}
+	if !strings.Contains(got[1], "alpha: 1 status change") || !strings.Contains(got[2], "beta: 1 status change") {
+		t.Fatalf("unexpected messages at stop %v", got[1:])
+	}
+	if len(m.pending) != 0 {
+		t.Fatalf("expected nothing pending after Stop, got %d", len(m.pending))
+	}
+}
+
+func TestMatrixIgnoresUnselectedKinds(t *testing.T) {
+	m := NewMatrix(settings.MatrixSettings{Events: []string{"proposal"}}, "monitor-1")
+	m.Handle(downEvent("alpha", monitor.StatusEventTransition))
+	if len(m.pending) != 0 {
+		t.Fatalf("transition events should be ignored when only proposals are selected")
+	}
+}
@@ -0,0 +1,72 @@
+// Package notify delivers this monitor's status events to operators. Events
+// come from monitor.AddStatusListener; each sink queues them and delivers on
+// its own goroutine so check workers are never held up by a slow endpoint.
+package notify
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/ibp-network/ibp-geodns-libs/logging"
+
+	"github.com/ibp-network/ibp-geodns-monitor/src/monitor"
+	"github.com/ibp-network/ibp-geodns-monitor/src/settings"
+)
+
+type sink interface {
+	Handle(ev monitor.StatusEvent)
+	Stop()
+}
+
+var (
+	mu      sync.Mutex
+	sinks   []sink
+	removes []func()
+)
+
+// Init starts the notifiers enabled in s. source names this monitor in
+// messages and events; webhook queues are kept under workDir.
+func Init(s settings.Settings, source, workDir string) {
+	var started []sink
+
+	if s.Matrix.Enabled == 1 {
+		m := NewMatrix(s.Matrix, source)
+		m.Start(time.Second)
+		started = append(started, m)
+		log.Log(log.Info, "Matrix notifications enabled for room %s", s.Matrix.RoomID)
+	}
+
+	for _, ws := range s.Webhooks {
+		w, err := NewWebhook(ws, source, filepath.Join(workDir, "webhooks"))
+		if err != nil {
+			log.Log(log.Error, "Webhook %s disabled: %v", ws.Name, err)
+			continue
+		}
+		w.Start()
+		started = append(started, w)
+		log.Log(log.Info, "Webhook %s enabled for %s", ws.Name, ws.URL)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, sk := range started {
+		sinks = append(sinks, sk)
+		removes = append(removes, monitor.AddStatusListener(sk.Handle))
+	}
+}
+
+// Shutdown stops the notifiers started by Init.
+func Shutdown() {
+	mu.Lock()
+	stopping, rms := sinks, removes
+	sinks, removes = nil, nil
+	mu.Unlock()
+
+	for _, rm := range rms {
+		rm()
+	}
+	for _, sk := range stopping {
+		sk.Stop()
+	}
+}
@@ -0,0 +1,411 @@
+package notify
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/ibp-network/ibp-geodns-libs/logging"
+
+	"github.com/ibp-network/ibp-geodns-monitor/src/monitor"
+	"github.com/ibp-network/ibp-geodns-monitor/src/settings"
+)
+
+const (
+	// SignatureHeader carries "sha256=<hex HMAC-SHA256>" keyed by the target's
+	// Secret, computed over the TimestampHeader value, a '.', and the body.
+	SignatureHeader = "X-IBP-Signature"
+	// TimestampHeader is the Unix time the request was signed; receivers
+	// should reject stale values so a captured request cannot be replayed.
+	TimestampHeader = "X-IBP-Timestamp"
+)
+
+// WebhookEvent is the JSON body posted to webhook targets.
+type WebhookEvent struct {
+	ID        string
+	Source    string
+	Kind      string
+	Time      time.Time
+	Type      string
+	Check     string
+	Member    string
+	Service   string `json:",omitempty"`
+	Domain    string `json:",omitempty"`
+	Endpoint  string `json:",omitempty"`
+	IsIPv6    bool
+	Status    bool
+	ErrorText string                 `json:",omitempty"`
+	Data      map[string]interface{} `json:",omitempty"`
+}
+
+// Webhook delivers events to one target in order. Undelivered events are
+// mirrored to a JSON-lines file so they survive a restart. Only the delivery
+// goroutine touches the file; Handle just queues in memory.
+type Webhook struct {
+	name       string
+	url        string
+	secret     string
+	source     string
+	events     map[string]bool
+	checks     map[string]bool
+	members    map[string]bool
+	maxQueue   int
+	path       string
+	client     *http.Client
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	mu        sync.Mutex
+	queue     []WebhookEvent
+	unwritten int  // events at the tail of queue not yet appended to the file
+	rewrite   bool // the file no longer matches a prefix of queue
+
+	wake   chan struct{}
+	stopCh chan struct{}
+	done   chan struct{}
+}
+
+// NewWebhook returns the target described by s, reloading any events left
+// queued under dir by a previous run.
+func NewWebhook(s settings.WebhookSettings, source, dir string) (*Webhook, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create webhook queue dir: %w", err)
+	}
+
+	w := &Webhook{
+		name:       s.Name,
+		url:        s.URL,
+		secret:     s.Secret,
+		source:     source,
+		events:     lowerSet(s.Events),
+		checks:     lowerSet(s.Checks),
+		members:    lowerSet(s.Members),
+		maxQueue:   max(s.MaxQueue, 1),
+		path:       filepath.Join(dir, s.Name+".jsonl"),
+		client:     &http.Client{Timeout: 15 * time.Second},
+		minBackoff: 2 * time.Second,
+		maxBackoff: 5 * time.Minute,
+		wake:       make(chan struct{}, 1),
+	}
+
+	queue, err := loadWebhookQueue(w.path)
+	if err != nil {
+		return nil, err
+	}
+	if over := len(queue) - w.maxQueue; over > 0 {
+		queue = queue[over:]
+	}
+	w.queue = queue
+	// Rewrite once so trimmed or torn lines are not appended to.
+	w.rewrite = true
+	if len(queue) > 0 {
+		log.Log(log.Info, "Webhook %s resuming with %d queued event(s)", w.name, len(queue))
+	}
+	return w, nil
+}
+
+func lowerSet(values []string) map[string]bool {
+	out := make(map[string]bool, len(values))
+	for _, v := range values {
+		out[strings.ToLower(v)] = true
+	}
+	return out
+}
+
+func (w *Webhook) matches(ev monitor.StatusEvent) bool {
+	if len(w.events) > 0 && !w.events[ev.Kind] {
+		return false
+	}
+	if len(w.checks) > 0 && !w.checks[strings.ToLower(ev.CheckName)] {
+		return false
+	}
+	if len(w.members) > 0 && !w.members[strings.ToLower(ev.Member)] {
+		return false
+	}
+	return true
+}
+
+// Handle queues ev for delivery if it passes the target's filters. When the
+// queue is full the oldest event is dropped. It does no I/O, since it runs on
+// the check worker.
+func (w *Webhook) Handle(ev monitor.StatusEvent) {
+	if !w.matches(ev) {
+		return
+	}
+
+	event := WebhookEvent{
+		ID:        newEventID(),
+		Source:    w.source,
+		Kind:      ev.Kind,
+		Time:      ev.Time.UTC(),
+		Type:      ev.Type,
+		Check:     ev.CheckName,
+		Member:    ev.Member,
+		Service:   ev.Service,
+		Domain:    ev.Domain,
+		Endpoint:  ev.Endpoint,
+		IsIPv6:    ev.IsIPv6,
+		Status:    ev.Status,
+		ErrorText: ev.ErrorText,
+		Data:      ev.Data,
+	}
+
+	w.mu.Lock()
+	if len(w.queue) >= w.maxQueue {
+		log.Log(log.Warn, "Webhook %s queue full; dropping event %s", w.name, w.queue[0].ID)
+		w.queue = w.queue[1:]
+		w.rewrite = true
+	}
+	w.queue = append(w.queue, event)
+	w.unwritten = min(w.unwritten+1, len(w.queue))
+	w.mu.Unlock()
+
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start delivers queued events until Stop, backing off after failures.
+func (w *Webhook) Start() {
+	w.stopCh = make(chan struct{})
+	w.done = make(chan struct{})
+	go func() {
+		defer close(w.done)
+		var backoff time.Duration
+		for {
+			w.flush()
+			if w.pending() == 0 {
+				select {
+				case <-w.wake:
+					continue
+				case <-w.stopCh:
+					return
+				}
+			}
+
+			if w.deliverNext() {
+				backoff = 0
+				continue
+			}
+			backoff = nextBackoff(backoff, w.minBackoff, w.maxBackoff)
+			select {
+			case <-time.After(backoff):
+			case <-w.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends delivery. Undelivered events stay in the queue file.
+func (w *Webhook) Stop() {
+	if w.stopCh != nil {
+		close(w.stopCh)
+		<-w.done
+		w.stopCh = nil
+	}
+	w.flush()
+}
+
+func nextBackoff(cur, minBackoff, maxBackoff time.Duration) time.Duration {
+	if cur <= 0 {
+		return minBackoff
+	}
+	return min(cur*2, maxBackoff)
+}
+
+func (w *Webhook) pending() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.queue)
+}
+
+// deliverNext posts the oldest queued event. It returns false when the
+// delivery should be retried after a backoff.
+func (w *Webhook) deliverNext() bool {
+	w.mu.Lock()
+	if len(w.queue) == 0 {
+		w.mu.Unlock()
+		return true
+	}
+	event := w.queue[0]
+	w.mu.Unlock()
+
+	err := w.post(event)
+	if err != nil {
+		var permanent *permanentError
+		if !errors.As(err, &permanent) {
+			log.Log(log.Debug, "Webhook %s delivery of %s failed: %v", w.name, event.ID, err)
+			return false
+		}
+		log.Log(log.Warn, "Webhook %s rejected event %s; dropping it: %v", w.name, event.ID, err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	// The event may already have been dropped to make room while posting.
+	if len(w.queue) > 0 && w.queue[0].ID == event.ID {
+		w.queue = w.queue[1:]
+		w.unwritten = min(w.unwritten, len(w.queue))
+		w.rewrite = true
+	}
+	return true
+}
+
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+func (w *Webhook) post(event WebhookEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return &permanentError{err: err}
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return &permanentError{err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-IBP-Event-ID", event.ID)
+	if w.secret != "" {
+		ts := time.Now().Unix()
+		req.Header.Set(TimestampHeader, strconv.FormatInt(ts, 10))
+		req.Header.Set(SignatureHeader, Sign(w.secret, ts, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return fmt.Errorf("target returned %d", resp.StatusCode)
+	default:
+		return &permanentError{err: fmt.Errorf("target returned %d", resp.StatusCode)}
+	}
+}
+
+// Sign returns the SignatureHeader value for body sent at Unix time timestamp.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newEventID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// flush brings the queue file up to date: new events are appended, and the
+// file is rewritten only after events left the head of the queue. Status
+// events are rare enough that such a rewrite is cheap.
+func (w *Webhook) flush() {
+	w.mu.Lock()
+	rewrite := w.rewrite
+	var events []WebhookEvent
+	if rewrite {
+		events = slices.Clone(w.queue)
+	} else {
+		events = slices.Clone(w.queue[len(w.queue)-w.unwritten:])
+	}
+	w.rewrite, w.unwritten = false, 0
+	w.mu.Unlock()
+
+	if !rewrite && len(events) == 0 {
+		return
+	}
+	err := w.writeQueue(events, rewrite)
+	if err != nil {
+		log.Log(log.Warn, "Webhook %s queue not persisted: %v", w.name, err)
+		w.mu.Lock()
+		w.rewrite = true
+		w.mu.Unlock()
+	}
+}
+
+// writeQueue appends events to the queue file, or replaces the file with
+// them when replace is set.
+func (w *Webhook) writeQueue(events []WebhookEvent, replace bool) error {
+	if replace && len(events) == 0 {
+		if err := os.Remove(w.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	path, flags := w.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY
+	if replace {
+		path, flags = w.path+".tmp", os.O_CREATE|os.O_TRUNC|os.O_WRONLY
+	}
+	f, err := os.OpenFile(path, flags, 0o600)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(f)
+	enc := json.NewEncoder(bw)
+	for _, ev := range events {
+		if err := enc.Encode(ev); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if replace {
+		return os.Rename(path, w.path)
+	}
+	return nil
+}
+
+func loadWebhookQueue(path string) ([]WebhookEvent, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("open webhook queue: %w", err)
+	}
+	defer f.Close()
+
+	var out []WebhookEvent
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var ev WebhookEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			continue
+		}
+		out = append(out, ev)
+	}
+	return out, scanner.Err()
+}
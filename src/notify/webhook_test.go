@@ -0,0 +1,199 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ibp-network/ibp-geodns-monitor/src/monitor"
+	"github.com/ibp-network/ibp-geodns-monitor/src/settings"
+)
+
+// fakeReceiver records verified webhook bodies and answers with statuses in order.
+type fakeReceiver struct {
+	mu       sync.Mutex
+	secret   string
+	events   []WebhookEvent
+	statuses []int
+	badSigs  int
+}
+
+func (f *fakeReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	ts, err := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+	if err != nil || time.Since(time.Unix(ts, 0)).Abs() > 5*time.Minute ||
+		r.Header.Get(SignatureHeader) != Sign(f.secret, ts, body) {
+		f.badSigs++
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	status := http.StatusNoContent
+	if len(f.statuses) > 0 {
+		status, f.statuses = f.statuses[0], f.statuses[1:]
+	}
+	if status < 300 {
+		var ev WebhookEvent
+		_ = json.Unmarshal(body, &ev)
+		f.events = append(f.events, ev)
+	}
+	w.WriteHeader(status)
+}
+
+func (f *fakeReceiver) received() []WebhookEvent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]WebhookEvent(nil), f.events...)
+}
+
+func newTestWebhook(t *testing.T, rcv *fakeReceiver, dir string, s settings.WebhookSettings) *Webhook {
+	t.Helper()
+	srv := httptest.NewServer(rcv)
+	t.Cleanup(srv.Close)
+
+	s.URL = srv.URL
+	s.Secret = rcv.secret
+	if s.Name == "" {
+		s.Name = "test"
+	}
+	if s.MaxQueue == 0 {
+		s.MaxQueue = 10
+	}
+	w, err := NewWebhook(s, "mon1", dir)
+	if err != nil {
+		t.Fatalf("NewWebhook: %v", err)
+	}
+	w.minBackoff = 10 * time.Millisecond
+	w.maxBackoff = 20 * time.Millisecond
+	return w
+}
+
+func waitForEvents(t *testing.T, rcv *fakeReceiver, n int) []WebhookEvent {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if got := rcv.received(); len(got) >= n {
+			return got
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("expected %d delivered events, got %d", n, len(rcv.received()))
+	return nil
+}
+
+func testEvent(check, member string) monitor.StatusEvent {
+	return monitor.StatusEvent{
+		Kind:      monitor.StatusEventTransition,
+		Type:      "endpoint",
+		CheckName: check,
+		Member:    member,
+		Endpoint:  "wss://rpc.example.com",
+		Time:      time.Now(),
+	}
+}
+
+func TestWebhookDeliversSignedEventsAfterRetry(t *testing.T) {
+	rcv := &fakeReceiver{secret: "s3cret", statuses: []int{http.StatusServiceUnavailable, http.StatusOK}}
+	w := newTestWebhook(t, rcv, t.TempDir(), settings.WebhookSettings{})
+	w.Start()
+	defer w.Stop()
+
+	w.Handle(testEvent("wss", "alpha"))
+	w.Handle(testEvent("rpc", "alpha"))
+
+	got := waitForEvents(t, rcv, 2)
+	if got[0].Check != "wss" || got[1].Check != "rpc" {
+		t.Fatalf("events delivered out of order: %+v", got)
+	}
+	if got[0].Source != "mon1" || got[0].ID == "" {
+		t.Fatalf("unexpected event %+v", got[0])
+	}
+	if rcv.badSigs != 0 {
+		t.Fatalf("expected valid signatures, got %d bad", rcv.badSigs)
+	}
+}
+
+func TestWebhookFiltersChecksAndMembers(t *testing.T) {
+	rcv := &fakeReceiver{secret: "s3cret"}
+	w := newTestWebhook(t, rcv, t.TempDir(), settings.WebhookSettings{
+		Checks:  []string{"wss"},
+		Members: []string{"Alpha"},
+	})
+
+	w.Handle(testEvent("wss", "beta"))
+	w.Handle(testEvent("rpc", "alpha"))
+	w.Handle(testEvent("wss", "alpha"))
+	if n := w.pending(); n != 1 {
+		t.Fatalf("expected 1 queued event after filtering, got %d", n)
+	}
+}
+
+func TestWebhookQueueSurvivesRestartAndIsBounded(t *testing.T) {
+	dir := t.TempDir()
+	rcv := &fakeReceiver{secret: "s3cret"}
+	w := newTestWebhook(t, rcv, dir, settings.WebhookSettings{MaxQueue: 2})
+
+	w.Handle(testEvent("a", "alpha"))
+	w.Handle(testEvent("b", "alpha"))
+	w.Handle(testEvent("c", "alpha"))
+	w.Stop()
+
+	restarted := newTestWebhook(t, rcv, dir, settings.WebhookSettings{MaxQueue: 2})
+	restarted.Start()
+	defer restarted.Stop()
+
+	got := waitForEvents(t, rcv, 2)
+	if got[0].Check != "b" || got[1].Check != "c" {
+		t.Fatalf("expected oldest event dropped and the rest replayed, got %+v", got)
+	}
+}
+
+func TestWebhookDropsRejectedEvents(t *testing.T) {
+	rcv := &fakeReceiver{secret: "s3cret", statuses: []int{http.StatusBadRequest}}
+	w := newTestWebhook(t, rcv, t.TempDir(), settings.WebhookSettings{})
+	w.Start()
+	defer w.Stop()
+
+	w.Handle(testEvent("a", "alpha"))
+	w.Handle(testEvent("b", "alpha"))
+
+	got := waitForEvents(t, rcv, 1)
+	if got[0].Check != "b" {
+		t.Fatalf("expected rejected event to be dropped, got %+v", got)
+	}
+}
+
+func TestSignatureCoversTimestamp(t *testing.T) {
+	body := []byte(`{"ID":"1"}`)
+	if Sign("k", 100, body) == Sign("k", 101, body) {
+		t.Fatalf("expected the signature to change with the timestamp")
+	}
+}
+
+func TestWebhookHandleDoesNotWriteQueueFile(t *testing.T) {
+	dir := t.TempDir()
+	rcv := &fakeReceiver{secret: "s3cret"}
+	w := newTestWebhook(t, rcv, dir, settings.WebhookSettings{})
+
+	w.Handle(testEvent("a", "alpha"))
+	if _, err := os.Stat(w.path); !os.IsNotExist(err) {
+		t.Fatalf("expected Handle to leave the file to the delivery goroutine, stat err %v", err)
+	}
+
+	w.Handle(testEvent("b", "alpha"))
+	w.flush()
+	w.Handle(testEvent("c", "alpha"))
+	w.flush()
+	queue, err := loadWebhookQueue(w.path)
+	if err != nil || len(queue) != 3 || queue[2].Check != "c" {
+		t.Fatalf("expected three queued events on disk, got %+v (%v)", queue, err)
+	}
+}
@@ -0,0 +1,171 @@
+// Package settings reads the monitor-only sections of the config file. The
+// shared sections (System, Nats, Checks, ...) are parsed by ibp-geodns-libs;
+// encoding/json ignores unknown keys on both sides, so they share one file.
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sync"
+)
+
+type Settings struct {
+	History      HistorySettings
+	Matrix       MatrixSettings
+	Webhooks     []WebhookSettings
+	CheckWorkers CheckWorkersSettings
+	State        StateSettings
+}
+
+type HistorySettings struct {
+	Enabled       int
+	RetentionDays int
+	Dir           string // defaults to <System.WorkDir>/history
+}
+
+// StateSettings controls the snapshot of last-run times and local results
+// kept in <System.WorkDir>/monitor-state.json across restarts.
+type StateSettings struct {
+	MaxAgeMinutes       int // entries older than this are discarded on restore
+	SaveIntervalSeconds int // how often the snapshot is written besides shutdown
+}
+
+// CheckWorkersSettings holds the monitor-only keys of the shared CheckWorkers
+// section; numWorkers is still read by ibp-geodns-libs.
+type CheckWorkersSettings struct {
+	MaxChecksPerSecond     float64 // global dispatch rate; 0 leaves only the worker pool as a limit
+	SeparationInterval     int     `json:"separationInterval"` // deprecated; ms between dispatches, used when MaxChecksPerSecond is unset
+	MaxConcurrentPerMember int     // checks running at once against one member; 0 for no limit
+	MaxConcurrentPerIP     int     // checks running at once against one member IP; 0 for no limit
+	JitterPercent          int     // per-node schedule offset as a percentage of each interval; 0 disables it
+}
+
+// MatrixSettings configures status notifications to a Matrix room through
+// the client-server API.
+type MatrixSettings struct {
+	Enabled            int
+	Homeserver         string // e.g. https://matrix.example.org
+	AccessToken        string
+	RoomID             string
+	Events             []string // "transition", "proposal"; both when empty
+	BatchSeconds       int      // how long events for a member are collected into one message
+	MinIntervalSeconds int      // minimum time between two messages about the same member
+}
+
+// WebhookSettings configures one outbound webhook target. Empty filters match everything.
+type WebhookSettings struct {
+	Name     string // identifies the target in logs and names its queue file
+	URL      string
+	Secret   string   // HMAC-SHA256 key for the X-IBP-Signature header
+	Events   []string // "transition", "proposal"
+	Checks   []string
+	Members  []string
+	MaxQueue int // events kept on disk while the target is unreachable
+}
+
+var webhookNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+var (
+	mu      sync.RWMutex
+	current = defaults()
+)
+
+func defaults() Settings {
+	return Settings{
+		History:      HistorySettings{RetentionDays: 14},
+		Matrix:       MatrixSettings{BatchSeconds: 30, MinIntervalSeconds: 300},
+		CheckWorkers: CheckWorkersSettings{JitterPercent: 10},
+		State:        StateSettings{MaxAgeMinutes: 60, SaveIntervalSeconds: 60},
+	}
+}
+
+// legacyChecksPerSecond matches the pace of the old per-worker tickers, one
+// check per separationInterval, for configs that still set it instead of
+// maxChecksPerSecond. Without either the rate is unlimited.
+func legacyChecksPerSecond(separationMs int) float64 {
+	if separationMs <= 0 {
+		return 0
+	}
+	return 1000 / float64(separationMs)
+}
+
+// Init loads the monitor-only settings from the config file at path.
+func Init(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read settings: %w", err)
+	}
+
+	s, err := parse(raw)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	current = s
+	mu.Unlock()
+	return nil
+}
+
+func parse(raw []byte) (Settings, error) {
+	s := defaults()
+	s.CheckWorkers.MaxChecksPerSecond = -1 // unset until derived below
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return Settings{}, fmt.Errorf("parse settings: %w", err)
+	}
+	if s.History.RetentionDays <= 0 {
+		s.History.RetentionDays = defaults().History.RetentionDays
+	}
+	if s.State.MaxAgeMinutes <= 0 {
+		s.State.MaxAgeMinutes = defaults().State.MaxAgeMinutes
+	}
+	if s.State.SaveIntervalSeconds <= 0 {
+		s.State.SaveIntervalSeconds = defaults().State.SaveIntervalSeconds
+	}
+	if s.Matrix.Enabled == 1 && (s.Matrix.Homeserver == "" || s.Matrix.AccessToken == "" || s.Matrix.RoomID == "") {
+		return Settings{}, fmt.Errorf("parse settings: Matrix requires Homeserver, AccessToken and RoomID")
+	}
+	seen := make(map[string]bool, len(s.Webhooks))
+	for i := range s.Webhooks {
+		w := &s.Webhooks[i]
+		if w.Name == "" || w.URL == "" {
+			return Settings{}, fmt.Errorf("parse settings: webhook %d requires Name and URL", i)
+		}
+		if !webhookNamePattern.MatchString(w.Name) {
+			return Settings{}, fmt.Errorf("parse settings: webhook name %q may only contain letters, digits, '.', '_' and '-'", w.Name)
+		}
+		if seen[w.Name] {
+			return Settings{}, fmt.Errorf("parse settings: duplicate webhook name %q", w.Name)
+		}
+		seen[w.Name] = true
+		if w.MaxQueue <= 0 {
+			w.MaxQueue = 1000
+		}
+	}
+	if s.CheckWorkers.MaxChecksPerSecond < 0 {
+		s.CheckWorkers.MaxChecksPerSecond = legacyChecksPerSecond(s.CheckWorkers.SeparationInterval)
+	}
+	if s.CheckWorkers.MaxConcurrentPerMember < 0 {
+		s.CheckWorkers.MaxConcurrentPerMember = 0
+	}
+	if s.CheckWorkers.MaxConcurrentPerIP < 0 {
+		s.CheckWorkers.MaxConcurrentPerIP = 0
+	}
+	s.CheckWorkers.JitterPercent = min(max(s.CheckWorkers.JitterPercent, 0), 100)
+	if s.Matrix.BatchSeconds < 0 {
+		s.Matrix.BatchSeconds = 0
+	}
+	if s.Matrix.MinIntervalSeconds < 0 {
+		s.Matrix.MinIntervalSeconds = 0
+	}
+	return s, nil
+}
+
+// Get returns the loaded settings, or the defaults before Init.
+func Get() Settings {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
@@ -0,0 +1,122 @@
+package settings
+
+import "testing"
+
+func TestParseKeepsDefaultsForMissingSections(t *testing.T) {
+	s, err := parse([]byte(`{"System": {"WorkDir": "/tmp"}, "Checks": []}`))
+	if err != nil {
+		t.Fatalf("parse returned error: %v", err)
+	}
+	if s.History.Enabled != 0 || s.History.RetentionDays != 14 {
+		t.Fatalf("expected default history settings, got %#v", s.History)
+	}
+}
+
+func TestParseReadsHistorySection(t *testing.T) {
+	s, err := parse([]byte(`{"History": {"Enabled": 1, "RetentionDays": 30, "Dir": "/var/lib/ibp"}}`))
+	if err != nil {
+		t.Fatalf("parse returned error: %v", err)
+	}
+	if s.History.Enabled != 1 || s.History.RetentionDays != 30 || s.History.Dir != "/var/lib/ibp" {
+		t.Fatalf("unexpected history settings %#v", s.History)
+	}
+}
+
+func TestParseReadsMatrixSection(t *testing.T) {
+	s, err := parse([]byte(`{"Matrix": {"Enabled": 1, "Homeserver": "https://matrix.example.org", "AccessToken": "tok", "RoomID": "!ops:example.org", "Events": ["proposal"]}}`))
+	if err != nil {
+		t.Fatalf("parse returned error: %v", err)
+	}
+	if s.Matrix.RoomID != "!ops:example.org" || len(s.Matrix.Events) != 1 {
+		t.Fatalf("unexpected matrix settings %#v", s.Matrix)
+	}
+	if s.Matrix.BatchSeconds != 30 || s.Matrix.MinIntervalSeconds != 300 {
+		t.Fatalf("expected default batching, got %#v", s.Matrix)
+	}
+}
+
+func TestParseRejectsIncompleteMatrixSection(t *testing.T) {
+	if _, err := parse([]byte(`{"Matrix": {"Enabled": 1, "Homeserver": "https://matrix.example.org"}}`)); err == nil {
+		t.Fatalf("expected enabled Matrix without a room and token to fail")
+	}
+}
+
+func TestParseReadsWebhooks(t *testing.T) {
+	s, err := parse([]byte(`{"Webhooks": [{"Name": "incidents", "URL": "https://hooks.example.org/ibp", "Secret": "k", "Members": ["alpha"]}]}`))
+	if err != nil {
+		t.Fatalf("parse returned error: %v", err)
+	}
+	if len(s.Webhooks) != 1 || s.Webhooks[0].MaxQueue != 1000 || s.Webhooks[0].Members[0] != "alpha" {
+		t.Fatalf("unexpected webhook settings %#v", s.Webhooks)
+	}
+}
+
+func TestParseRejectsInvalidWebhooks(t *testing.T) {
+	for _, raw := range []string{
+		`{"Webhooks": [{"URL": "https://hooks.example.org"}]}`,
+		`{"Webhooks": [{"Name": "../x", "URL": "https://hooks.example.org"}]}`,
+		`{"Webhooks": [{"Name": "a", "URL": "https://one"}, {"Name": "a", "URL": "https://two"}]}`,
+	} {
+		if _, err := parse([]byte(raw)); err == nil {
+			t.Fatalf("expected %s to be rejected", raw)
+		}
+	}
+}
+
+func TestParseReadsCheckWorkersAlongsideSharedKeys(t *testing.T) {
+	s, err := parse([]byte(`{"CheckWorkers": {"numWorkers": 100, "maxChecksPerSecond": 2.5, "MaxConcurrentPerMember": 4, "MaxConcurrentPerIP": -1}}`))
+	if err != nil {
+		t.Fatalf("parse returned error: %v", err)
+	}
+	if s.CheckWorkers.MaxChecksPerSecond != 2.5 || s.CheckWorkers.MaxConcurrentPerMember != 4 || s.CheckWorkers.MaxConcurrentPerIP != 0 {
+		t.Fatalf("unexpected check worker settings %#v", s.CheckWorkers)
+	}
+}
+
+func TestParseJitterPercent(t *testing.T) {
+	cases := map[string]int{
+		`{}`:                                     10,
+		`{"CheckWorkers": {"JitterPercent": 0}}`: 0,
+		`{"CheckWorkers": {"JitterPercent": 25}}`:  25,
+		`{"CheckWorkers": {"JitterPercent": 250}}`: 100,
+		`{"CheckWorkers": {"JitterPercent": -5}}`:  0,
+	}
+	for raw, want := range cases {
+		s, err := parse([]byte(raw))
+		if err != nil {
+			t.Fatalf("parse(%s) returned error: %v", raw, err)
+		}
+		if s.CheckWorkers.JitterPercent != want {
+			t.Fatalf("parse(%s): expected JitterPercent %d, got %d", raw, want, s.CheckWorkers.JitterPercent)
+		}
+	}
+}
+
+func TestParseStateDefaults(t *testing.T) {
+	s, err := parse([]byte(`{"State": {"MaxAgeMinutes": 15, "SaveIntervalSeconds": -1}}`))
+	if err != nil {
+		t.Fatalf("parse returned error: %v", err)
+	}
+	if s.State.MaxAgeMinutes != 15 || s.State.SaveIntervalSeconds != 60 {
+		t.Fatalf("unexpected state settings %#v", s.State)
+	}
+}
+
+func TestParseDerivesRateFromSeparationInterval(t *testing.T) {
+	cases := map[string]float64{
+		`{}`: 0,
+		`{"CheckWorkers": {"separationInterval": 0}}`:                              0,
+		`{"CheckWorkers": {"separationInterval": 100}}`:                            10,
+		`{"CheckWorkers": {"separationInterval": 100, "maxChecksPerSecond": 0}}`:   0,
+		`{"CheckWorkers": {"separationInterval": 100, "maxChecksPerSecond": 2.5}}`: 2.5,
+	}
+	for raw, want := range cases {
+		s, err := parse([]byte(raw))
+		if err != nil {
+			t.Fatalf("parse(%s) returned error: %v", raw, err)
+		}
+		if s.CheckWorkers.MaxChecksPerSecond != want {
+			t.Fatalf("parse(%s): expected %v checks/s, got %v", raw, want, s.CheckWorkers.MaxChecksPerSecond)
+		}
+	}
+}